@@ -10,45 +10,20 @@ import (
 type Page struct {
 	File       string
 	OutFile    string
-	Meta       map[string]string
+	Meta       map[string]Value
 	Content    []string
 	IsBlogPost bool
 }
 
 func (self *Page) GetSlug() string {
-	return Helpers.URLSafe(self.Meta["page.title"])
+	return Helpers.URLSafe(self.Meta["page.title"].String())
 }
 
 func (self *Page) GetPermalink(structure string) string {
 	permalink := Helpers.Replace(structure, "%slug%", self.GetSlug())
-	permalink = Helpers.Replace(permalink, "%year%", self.Meta["page.date_year"])
-	permalink = Helpers.Replace(permalink, "%month%", self.Meta["page.date_month"])
-	permalink = Helpers.Replace(permalink, "%day%", self.Meta["page.date_day"])
+	permalink = Helpers.Replace(permalink, "%year%", self.Meta["page.date_year"].String())
+	permalink = Helpers.Replace(permalink, "%month%", self.Meta["page.date_month"].String())
+	permalink = Helpers.Replace(permalink, "%day%", self.Meta["page.date_day"].String())
 
 	return permalink
 }
-
-/**
- * An Inline command
- */
-type InlineCommand struct {
-	Control   string // Type of command (if, include, while, etc...)
-	Condition string // Control condition
-	IfTrue    string
-	IfFalse   string
-	StartLine int // Line the command starts at
-	EndLine   int // Line the command ends at
-}
-
-/**
- * A multiline COmmand
- */
-type MultilineCommand struct {
-	Control   string // Type of command (if, include, while, etc...)
-	Condition string // Control condition
-	IfTrue    []string
-	IfFalse   []string
-	StartLine int // Line the command starts at
-	EndLine   int // Line the command ends at
-	State     int
-}
@@ -0,0 +1,37 @@
+package DataTypes
+
+/**
+ * A node in a parsed expression
+ */
+type Expression interface {
+	Source() string
+}
+
+/**
+ * A value, variable or concatenation, evaluated as a whole
+ */
+type OperandExpression struct {
+	Text string
+}
+
+/**
+ * !operand, not operand
+ */
+type NotExpression struct {
+	Operand Expression
+	Text    string
+}
+
+/**
+ * lhs operator rhs, for logical (&&, ||), comparison and text (in, contains, startswith, endswith) operators
+ */
+type BinaryExpression struct {
+	Operator string
+	Left     Expression
+	Right    Expression
+	Text     string
+}
+
+func (self OperandExpression) Source() string { return self.Text }
+func (self NotExpression) Source() string     { return self.Text }
+func (self BinaryExpression) Source() string  { return self.Text }
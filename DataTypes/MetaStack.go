@@ -1,8 +1,22 @@
 package DataTypes
 
 
+/**
+ * What kind of scope variables are in, each foreach, include and macro call adds one
+ */
+type ScopeKind int
+
+const (
+    PageScope ScopeKind = iota // The page being expanded, the outermost scope
+    IncludeScope               // A file that is included, holds the include.* arguments
+    LoopScope                  // One item of a foreach loop
+    MacroScope                 // A macro call, it only sees its parameters and the page
+)
+
+
 type MetaStack struct {
-    items []map[string]string
+    items []map[string]Value
+    kinds []ScopeKind
 
 }
 
@@ -16,19 +30,55 @@ func (self MetaStack) Length() (int) {
 /**
  * Peeks at the top of the stack
  */
-func (self MetaStack) Peek() (map[string]string) {
+func (self MetaStack) Peek() (map[string]Value) {
     if self.Length() > 0 {
         return self.items[len(self.items) - 1]
     } else {
-        return make(map[string]string)
+        return make(map[string]Value)
+    }
+}
+
+/**
+ * Name.........: Scope
+ * Parameters...: index (int) - the position in the stack, 0 is the bottom
+ * Return.......: map[string]Value - the variables of the scope
+ *                ScopeKind - what kind of scope it is
+ * Description..: Gets a scope anywhere in the stack
+ */
+func (self MetaStack) Scope(index int) (map[string]Value, ScopeKind) {
+    return self.items[index], self.kinds[index]
+}
+
+/**
+ * Name.........: Nearest
+ * Parameters...: kind (ScopeKind) - the kind of scope to look for
+ *                boundaries (...ScopeKind) - kinds of scope the search stops at
+ * Return.......: map[string]Value - the variables of the scope, empty if there is none
+ * Description..: Gets the scope of a kind that is closest to the top of the stack, without looking past
+ *                a boundary
+ */
+func (self MetaStack) Nearest(kind ScopeKind, boundaries ...ScopeKind) (map[string]Value) {
+    for i := self.Length() - 1; i >= 0; i-- {
+        if self.kinds[i] == kind {
+            return self.items[i]
+        }
+
+        for _, boundary := range boundaries {
+            if self.kinds[i] == boundary {
+                return make(map[string]Value)
+            }
+        }
     }
+
+    return make(map[string]Value)
 }
 
 /**
  * Pushes an item onto the stack
  */
-func (self *MetaStack) Push(item map[string]string) (int) {
+func (self *MetaStack) Push(item map[string]Value, kind ScopeKind) (int) {
     (*self).items = append((*self).items, item)
+    (*self).kinds = append((*self).kinds, kind)
 
     return (*self).Length()
 }
@@ -37,13 +87,14 @@ func (self *MetaStack) Push(item map[string]string) (int) {
 /**
  * Pops an item from the stack
  */
-func (self *MetaStack) Pop() (map[string]string, int) {
-    item := make(map[string]string)
+func (self *MetaStack) Pop() (map[string]Value, int) {
+    item := make(map[string]Value)
     length := (*self).Length()
 
     if length > 0 {
         item = (*self).items[length - 1]
         (*self).items = (*self).items[:length - 1]
+        (*self).kinds = (*self).kinds[:length - 1]
         length = (*self).Length()
     }
 
@@ -0,0 +1,276 @@
+package DataTypes
+
+/**
+ * The kinds of tokens the template lexer produces
+ */
+type TokenType int
+
+const (
+	TextToken    TokenType = iota // Plain text copied to the output
+	PrintToken                    // {{ expression }}
+	TagToken                      // {% command %}
+	CommentToken                  // {# comment #}, removed from the output
+)
+
+/**
+ * Options for reading templates
+ */
+type Syntax struct {
+	TagOpening     string // {%
+	TagClosing     string // %}
+	PrintOpening   string // {{
+	PrintClosing   string // }}
+	CommentOpening string // {#
+	CommentClosing string // #}
+	TrimTagLines   bool   // Also remove lines that have more than one command or comment, and nothing else
+}
+
+/**
+ * Name.........: Tag
+ * Parameters...: command (string) - the inside of the tag
+ * Return.......: string - the command between the tag delimiters, like {% end if %}
+ * Description..: Writes a command the way it is written in templates, for error messages
+ */
+func (self Syntax) Tag(command string) string {
+	return self.TagOpening + " " + command + " " + self.TagClosing
+}
+
+/**
+ * Name.........: Print
+ * Parameters...: expression (string) - the inside of the print
+ * Return.......: string - the expression between the print delimiters, like {{ super }}
+ * Description..: Writes a print the way it is written in templates, for error messages
+ */
+func (self Syntax) Print(expression string) string {
+	return self.PrintOpening + " " + expression + " " + self.PrintClosing
+}
+
+/**
+ * Where in the HTML a print is, so the value can be escaped the right way
+ */
+type HTMLContext int
+
+const (
+	TextContext         HTMLContext = iota // Between tags
+	AttributeContext                       // Inside of a tag or the value of an attribute
+	URLContext                             // At the start of the value of an attribute that is a URL (href, src, ...)
+	ScriptContext                          // Code in a <script> element or an on* attribute, outside of a string
+	ScriptStringContext                    // Inside of a string in a <script> element or an on* attribute
+	StyleContext                           // Inside of a <style> element or a style attribute
+)
+
+/**
+ * Whether a print is inside of an HTML tag, and if there are quotes around it
+ */
+type TagPosition int
+
+const (
+	OutsideTag    TagPosition = iota // Between tags, or in a script or style element
+	QuotedValue                      // The value of an attribute, in quotes
+	UnquotedValue                    // Anywhere else in a tag, whitespace or = would end the value
+)
+
+/**
+ * A single token of a template
+ */
+type Token struct {
+	Type       TokenType
+	Value      string // Text, or the trimmed inside of the tags
+	Line       int    // Line the token starts on
+	Standalone bool   // True if the tag was the only thing on its line
+	Indent     string // Indentation of a print that is the only thing on its line
+	TrimBefore bool   // {%- removes the whitespace before the tag
+	TrimAfter  bool   // -%} removes the whitespace after the tag
+	Context    HTMLContext
+	Position   TagPosition
+}
+
+/**
+ * A node in a parsed template
+ */
+type Node interface {
+	StartLine() int
+}
+
+/**
+ * Plain text
+ */
+type TextNode struct {
+	Text string
+	Line int
+}
+
+/**
+ * {{ expression }}
+ */
+type PrintNode struct {
+	Expression string
+	Indent     string
+	Context    HTMLContext
+	Position   TagPosition
+	Line       int
+}
+
+/**
+ * One condition of an if statement and what to evaluate when it is true
+ */
+type IfBranch struct {
+	Condition string
+	Body      []Node
+	Line      int
+}
+
+/**
+ * {% if condition %} ... {% else if condition %} ... {% else %} ... {% end if %}
+ */
+type IfNode struct {
+	Branches []IfBranch // The if, then every else if in order
+	Else     []Node
+	Line     int
+}
+
+/**
+ * A {% case value, value %} of a switch
+ */
+type SwitchCase struct {
+	Values []string // Expressions, the case is used if the value of the switch equals any of them
+	Body   []Node
+	Line   int
+}
+
+/**
+ * {% switch value %} {% case value %} ... {% default %} ... {% end switch %}
+ */
+type SwitchNode struct {
+	Value   string
+	Cases   []SwitchCase
+	Default []Node
+	Line    int
+}
+
+/**
+ * {% foreach collection as alias where condition order by key desc limit n offset n %} ... {% end foreach %}
+ */
+type ForeachNode struct {
+	Collection string // A collection of pages, a range like 1..5, or any value
+	Alias      string
+	Key        string // The alias of the key, when looping through a map with as key, value
+	Where      string // Condition every item has to meet
+	OrderBy    string // Expression to sort the items by
+	Descending bool
+	Limit      string
+	Offset     string
+	Body       []Node
+	Line       int
+}
+
+/**
+ * {% break %}, stops the foreach loop it is in
+ */
+type BreakNode struct {
+	Line int
+}
+
+/**
+ * {% continue %}, skips to the next item of the foreach loop it is in
+ */
+type ContinueNode struct {
+	Line int
+}
+
+/**
+ * {% set variable = value %}
+ */
+type SetNode struct {
+	Variable string
+	Value    string
+	Global   bool // Set for the whole page instead of the innermost scope
+	Line     int
+}
+
+/**
+ * {% capture variable %} ... {% end capture %}
+ */
+type CaptureNode struct {
+	Variable string
+	Body     []Node
+	Line     int
+}
+
+/**
+ * {% include file name=value %}
+ */
+type IncludeNode struct {
+	File       string
+	Arguments  map[string]string // Expressions given to the included file as include.name
+	Standalone bool
+	Line       int
+}
+
+/**
+ * {% extends template %}
+ */
+type ExtendsNode struct {
+	Template string
+	Line     int
+}
+
+/**
+ * {% block name %} ... {% end block %}
+ */
+type BlockNode struct {
+	Name string
+	Body []Node
+	Line int
+}
+
+/**
+ * {{ super }}, the same block in the template that is extended
+ */
+type SuperNode struct {
+	Line int
+}
+
+/**
+ * A parameter of a macro, the default is an expression, empty if the parameter is required
+ */
+type MacroParameter struct {
+	Name    string
+	Default string
+}
+
+/**
+ * {% macro name(parameter, parameter=default) %} ... {% end macro %}
+ */
+type MacroNode struct {
+	Name       string
+	Parameters []MacroParameter
+	Body       []Node
+	Namespace  string // The alias of the file the macro was imported from, its body calls the macros of that file first
+	Line       int
+}
+
+/**
+ * {% import file as alias %}
+ */
+type ImportNode struct {
+	File  string
+	Alias string // The macros of the file are used as alias.name, or just name if there is no alias
+	Line  int
+}
+
+func (self TextNode) StartLine() int     { return self.Line }
+func (self PrintNode) StartLine() int    { return self.Line }
+func (self IfNode) StartLine() int       { return self.Line }
+func (self SwitchNode) StartLine() int   { return self.Line }
+func (self ForeachNode) StartLine() int  { return self.Line }
+func (self BreakNode) StartLine() int    { return self.Line }
+func (self ContinueNode) StartLine() int { return self.Line }
+func (self SetNode) StartLine() int      { return self.Line }
+func (self CaptureNode) StartLine() int  { return self.Line }
+func (self IncludeNode) StartLine() int  { return self.Line }
+func (self ExtendsNode) StartLine() int  { return self.Line }
+func (self BlockNode) StartLine() int    { return self.Line }
+func (self SuperNode) StartLine() int    { return self.Line }
+func (self MacroNode) StartLine() int    { return self.Line }
+func (self ImportNode) StartLine() int   { return self.Line }
@@ -0,0 +1,417 @@
+package DataTypes
+
+import (
+	"bytes"
+	"daphne/Helpers"
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+/**
+ * The types a value can have
+ */
+type ValueKind int
+
+const (
+	NullValue ValueKind = iota // Does not exist
+	StringValue
+	NumberValue
+	BoolValue
+	DateValue
+	ListValue
+	MapValue
+)
+
+/**
+ * A typed value from the config, the meta of a page, or an expression
+ */
+type Value struct {
+	Kind ValueKind
+	Str  string // The text of the value, kept so numbers and dates print the way they were written
+	Num  float64
+	Bool bool
+	Date time.Time
+	List []Value
+	Map  map[string]Value
+	Safe bool // Trusted HTML that is printed without escaping it
+	JSON bool // Text that is already JSON, printed as it is in a script
+}
+
+var numberRegex, _ = regexp.Compile("^-?[0-9]+(\\.[0-9]+)?$")
+
+// Formats dates are read from, in the order they are tried
+var DateFormats = []string{"2006-01-02", time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04", "January 2, 2006"}
+
+/**
+ * Value constructors
+ */
+func NewString(str string) Value {
+	return Value{Kind: StringValue, Str: str}
+}
+
+func NewSafeString(str string) Value {
+	return Value{Kind: StringValue, Str: str, Safe: true}
+}
+
+func NewJSONString(str string) Value {
+	return Value{Kind: StringValue, Str: str, JSON: true}
+}
+
+func NewNumber(num float64) Value {
+	return Value{Kind: NumberValue, Num: num}
+}
+
+func NewBool(b bool) Value {
+	return Value{Kind: BoolValue, Bool: b}
+}
+
+func NewDate(date time.Time, str string) Value {
+	return Value{Kind: DateValue, Date: date, Str: str}
+}
+
+func NewList(items []Value) Value {
+	return Value{Kind: ListValue, List: items}
+}
+
+func NewMap(items map[string]Value) Value {
+	return Value{Kind: MapValue, Map: items}
+}
+
+/**
+ * Name.........: ParseValue
+ * Parameters...: raw (string) - the value as it was written
+ * Return.......: Value
+ * Description..: Reads a value from the config or the meta section of a page. Quoted text is a string,
+ *                [a, b] is a list, and true, false, numbers and dates get their own types
+ */
+func ParseValue(raw string) Value {
+	raw = Helpers.Trim(raw)
+
+	if Helpers.WrappedInQuotes(raw) {
+		return NewString(Helpers.StripQuotes(raw))
+	}
+
+	if len(raw) >= 2 && raw[:1] == "[" && raw[len(raw)-1:] == "]" {
+		items := []Value{}
+		for _, item := range SplitList(raw[1 : len(raw)-1]) {
+			items = append(items, ParseValue(item))
+		}
+		return NewList(items)
+	}
+
+	switch Helpers.ToLower(raw) {
+	case "true":
+		return NewBool(true)
+	case "false":
+		return NewBool(false)
+	}
+
+	if numberRegex.MatchString(raw) {
+		num, _ := strconv.ParseFloat(raw, 64)
+		return Value{Kind: NumberValue, Num: num, Str: raw}
+	}
+
+	if date, isDate := ParseDate(raw); isDate {
+		return NewDate(date, raw)
+	}
+
+	return NewString(raw)
+}
+
+/**
+ * Name.........: ParseDate
+ * Parameters...: value (string) - the date
+ * Return.......: time.Time - the parsed date
+ *                bool - false if it is not a date
+ * Description..: Reads a date in any of the formats Daphne uses
+ */
+func ParseDate(value string) (time.Time, bool) {
+	value = Helpers.Trim(value)
+
+	for _, format := range DateFormats {
+		date, err := time.Parse(format, value)
+		if err == nil {
+			return date, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+/**
+ * Name.........: SplitList
+ * Parameters...: list (string) - the inside of the brackets of a list
+ * Return.......: []string - the items
+ * Description..: Splits a list at commas that are not inside of quotes or brackets
+ */
+func SplitList(list string) []string {
+	items := []string{}
+
+	start := 0
+	quote := byte(0)
+	depth := 0
+
+	for i := 0; i < len(list); i++ {
+		c := list[i]
+
+		if quote != 0 {
+			if c == quote && list[i-1] != '\\' {
+				quote = 0
+			}
+			continue
+		}
+
+		switch c {
+		case '"', '\'':
+			quote = c
+		case '[', '(':
+			depth++
+		case ']', ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				items = append(items, Helpers.Trim(list[start:i]))
+				start = i + 1
+			}
+		}
+	}
+
+	if Helpers.Trim(list[start:]) != "" || len(items) > 0 {
+		items = append(items, Helpers.Trim(list[start:]))
+	}
+
+	return items
+}
+
+/**
+ * Name.........: String
+ * Return.......: string
+ * Description..: The value as it is printed
+ */
+func (self Value) String() string {
+	switch self.Kind {
+	case StringValue:
+		return self.Str
+
+	case NumberValue:
+		if self.Str != "" {
+			return self.Str
+		}
+		return strconv.FormatFloat(self.Num, 'f', -1, 64)
+
+	case BoolValue:
+		return strconv.FormatBool(self.Bool)
+
+	case DateValue:
+		if self.Str != "" {
+			return self.Str
+		}
+		return self.Date.Format("January 2, 2006")
+
+	case ListValue:
+		items := []string{}
+		for _, item := range self.List {
+			items = append(items, item.String())
+		}
+		return Helpers.Join(items, ", ")
+
+	case MapValue:
+		items := []string{}
+		for _, key := range self.Keys() {
+			items = append(items, key+": "+self.Map[key].String())
+		}
+		return Helpers.Join(items, ", ")
+	}
+
+	return ""
+}
+
+/**
+ * Name.........: Truthy
+ * Return.......: bool
+ * Description..: Whether the value counts as true in a condition. Null, false, 0, empty text and empty lists
+ *                and maps are false
+ */
+func (self Value) Truthy() bool {
+	switch self.Kind {
+	case StringValue:
+		return self.Str != ""
+	case NumberValue:
+		return self.Num != 0
+	case BoolValue:
+		return self.Bool
+	case DateValue:
+		return !self.Date.IsZero()
+	case ListValue:
+		return len(self.List) > 0
+	case MapValue:
+		return len(self.Map) > 0
+	}
+
+	return false
+}
+
+/**
+ * Name.........: AsNumber
+ * Return.......: float64 - the value as a number
+ *                bool - false if it is not a number
+ * Description..: Reads the value as a number, text is read as a number if it is an integer or a decimal
+ */
+func (self Value) AsNumber() (float64, bool) {
+	switch self.Kind {
+	case NumberValue:
+		return self.Num, true
+	case StringValue:
+		str := Helpers.Trim(self.Str)
+		if numberRegex.MatchString(str) {
+			num, err := strconv.ParseFloat(str, 64)
+			return num, err == nil
+		}
+	}
+
+	return 0, false
+}
+
+/**
+ * Name.........: AsDate
+ * Return.......: time.Time - the value as a date
+ *                bool - false if it is not a date
+ * Description..: Reads the value as a date, text is read as a date if it is in one of the DateFormats
+ */
+func (self Value) AsDate() (time.Time, bool) {
+	switch self.Kind {
+	case DateValue:
+		return self.Date, true
+	case StringValue:
+		return ParseDate(self.Str)
+	}
+
+	return time.Time{}, false
+}
+
+/**
+ * Name.........: IsNull
+ * Return.......: bool
+ * Description..: True if the value does not exist
+ */
+func (self Value) IsNull() bool {
+	return self.Kind == NullValue
+}
+
+/**
+ * Name.........: Keys
+ * Return.......: []string
+ * Description..: The keys of a map, sorted
+ */
+func (self Value) Keys() []string {
+	keys := []string{}
+	for key := range self.Map {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+/**
+ * Name.........: Property
+ * Parameters...: name (string) - the key of a map, the index of a list, or length
+ * Return.......: Value - the property, null if it does not exist
+ * Description..: Gets a property of a map or a list, length is the number of items in a list
+ */
+func (self Value) Property(name string) Value {
+	switch self.Kind {
+	case MapValue:
+		return self.Map[name]
+
+	case ListValue:
+		if name == "length" {
+			return NewNumber(float64(len(self.List)))
+		}
+
+		index, err := strconv.Atoi(name)
+		if err == nil && index >= 0 && index < len(self.List) {
+			return self.List[index]
+		}
+	}
+
+	return Value{}
+}
+
+/**
+ * Name.........: MarshalJSON
+ * Return.......: []byte - the value as JSON
+ *                error - any errors
+ * Description..: Encodes the value as JSON with the matching JSON type
+ */
+func (self Value) MarshalJSON() ([]byte, error) {
+	switch self.Kind {
+	case NullValue:
+		return []byte("null"), nil
+	case NumberValue:
+		return json.Marshal(self.Num)
+	case BoolValue:
+		return json.Marshal(self.Bool)
+	case ListValue:
+		return json.Marshal(self.List)
+	case MapValue:
+		return json.Marshal(self.Map)
+	}
+
+	return json.Marshal(self.String())
+}
+
+/**
+ * Name.........: UnmarshalJSON
+ * Parameters...: data ([]byte) - JSON
+ * Return.......: error - any errors
+ * Description..: Decodes JSON into the matching value, numbers print the way they were written and the keys
+ *                of objects are lower case, like the names of every other variable
+ */
+func (self *Value) UnmarshalJSON(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var raw interface{}
+	if err := decoder.Decode(&raw); err != nil {
+		return err
+	}
+
+	*self = fromJSON(raw)
+	return nil
+}
+
+/**
+ * Name.........: fromJSON
+ * Parameters...: raw (interface{}) - JSON decoded with numbers as json.Number
+ * Return.......: Value
+ * Description..: Converts decoded JSON into a value
+ */
+func fromJSON(raw interface{}) Value {
+	switch data := raw.(type) {
+	case string:
+		return NewString(data)
+	case bool:
+		return NewBool(data)
+	case json.Number:
+		num, _ := data.Float64()
+		return Value{Kind: NumberValue, Num: num, Str: data.String()}
+	case []interface{}:
+		items := []Value{}
+		for _, item := range data {
+			items = append(items, fromJSON(item))
+		}
+		return NewList(items)
+	case map[string]interface{}:
+		items := make(map[string]Value)
+		for key, item := range data {
+			items[Helpers.ToLower(key)] = fromJSON(item)
+		}
+		return NewMap(items)
+	}
+
+	return Value{}
+}
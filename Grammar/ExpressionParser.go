@@ -0,0 +1,392 @@
+package Grammar
+
+/**
+ * Expression := Or
+ * Or := And {("||" | "or") And}
+ * And := Not {("&&" | "and") Not}
+ * Not := ("!" | "not") Not | Comparison
+ * Comparison := Sum [(ComparisonOperator | TextOperator) Sum]
+ * Sum := Product {("+" | "-") Product}
+ * Product := Operand {("*" | "/" | "%") Operand}
+ * Operand := "(" Expression ")" | Term {Term}
+ * Term := String literal | word
+ *
+ * Where a value is expected, dates (2017-01-01), URLs (https://...), paths (/blog/) and negative numbers (-1)
+ * are words even though they have - or / in them
+ */
+import (
+	"daphne/DataTypes"
+	"daphne/Errors"
+	"daphne/Grammar/Operators"
+	"daphne/Helpers"
+	"regexp"
+	"strings"
+)
+
+/**
+ * The kinds of tokens in an expression
+ */
+const (
+	wordToken = iota
+	stringToken
+	operatorToken
+)
+
+/**
+ * A token of an expression, start and end are its position in the expression
+ */
+type expressionToken struct {
+	kind  int
+	value string
+	start int
+	end   int
+}
+
+/**
+ * Walks the tokens of an expression and builds the expression tree
+ */
+type expressionParser struct {
+	source string
+	tokens []expressionToken
+	pos    int
+}
+
+// Characters that end a word
+const operatorCharacters = "=!<>&|()+-*/%"
+
+// Words that have operator characters in them, only read where a value is expected
+var literalRegex, _ = regexp.Compile("^(?i)([0-9]{4}-[0-9]{1,2}-[0-9]{1,2}|[a-z][a-z0-9+.-]*://[^\\s\"'()]*|/[^\\s\"'=!<>&|()+*%]*|-[0-9]+(\\.[0-9]+)?)")
+
+/**
+ * Name.........: ParseExpression
+ * Parameters...: source (string) - the expression to parse
+ * Return.......: DataTypes.Expression - the parsed expression
+ *                Errors.Error - any errors
+ * Description..: Parses a condition into a tree, && binds tighter than ||, and both bind looser than
+ *                ! and the comparison operators. The arithmetic operators bind tighter than all of them
+ */
+func ParseExpression(source string) (DataTypes.Expression, Errors.Error) {
+	tokens, err := tokenizeExpression(source)
+	if err.HasError() {
+		return nil, err
+	}
+
+	if len(tokens) == 0 {
+		return nil, Errors.NewFatal("Missing condition")
+	}
+
+	parser := expressionParser{source: source, tokens: tokens}
+
+	expression, err := parser.parseOr()
+	if err.HasError() {
+		return nil, err
+	}
+
+	if parser.pos < len(parser.tokens) {
+		return nil, Errors.NewFatal("Unexpected ", parser.tokens[parser.pos].value, " in ", Helpers.Trim(source))
+	}
+
+	return expression, Errors.None()
+}
+
+/**
+ * Name.........: tokenizeExpression
+ * Parameters...: source (string) - the expression
+ * Return.......: []expressionToken - the tokens found
+ *                Errors.Error - any errors
+ * Description..: Splits an expression into words, string literals and operators
+ */
+func tokenizeExpression(source string) ([]expressionToken, Errors.Error) {
+	tokens := []expressionToken{}
+
+	for i := 0; i < len(source); {
+		c := source[i]
+
+		literal := ""
+		if expectsValue(tokens) {
+			literal = literalRegex.FindString(source[i:])
+		}
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case c == '"' || c == '\'':
+			end := i + 1
+			for end < len(source) && (source[end] != c || source[end-1] == '\\') {
+				end++
+			}
+			if end >= len(source) {
+				return nil, Errors.NewFatal("Missing closing ", string(c), " in ", Helpers.Trim(source))
+			}
+
+			tokens = append(tokens, expressionToken{kind: stringToken, value: source[i : end+1], start: i, end: end + 1})
+			i = end + 1
+
+		case literal == "" && strings.IndexByte(operatorCharacters, c) >= 0:
+			operator := source[i : i+1]
+			if i+2 <= len(source) && (Operators.IsComparisonOperator(source[i:i+2]) || Operators.IsLogicalOperator(source[i:i+2])) {
+				operator = source[i : i+2]
+			}
+
+			if !Operators.IsComparisonOperator(operator) && !Operators.IsLogicalOperator(operator) && !Operators.IsArithmeticOperator(operator) && operator != Operators.Logical.Not && operator != "(" && operator != ")" {
+				return nil, Errors.NewFatal("Unknown operator ", operator, " in ", Helpers.Trim(source))
+			}
+
+			tokens = append(tokens, expressionToken{kind: operatorToken, value: operator, start: i, end: i + len(operator)})
+			i += len(operator)
+
+		default:
+			end := i + len(literal)
+			for end < len(source) && !strings.ContainsAny(source[end:end+1], " \t\n\r\"'"+operatorCharacters) {
+				end++
+			}
+
+			word := source[i:end]
+			token := expressionToken{kind: wordToken, value: word, start: i, end: end}
+
+			// The word operators
+			if operator, isKeyword := Operators.LogicalKeyword(Helpers.ToLower(word)); isKeyword {
+				token = expressionToken{kind: operatorToken, value: operator, start: i, end: end}
+			} else if Operators.IsTextOperator(Helpers.ToLower(word)) {
+				token = expressionToken{kind: operatorToken, value: Helpers.ToLower(word), start: i, end: end}
+			}
+
+			tokens = append(tokens, token)
+			i = end
+		}
+	}
+
+	return tokens, Errors.None()
+}
+
+/**
+ * Name.........: expectsValue
+ * Parameters...: tokens ([]expressionToken) - the tokens read so far
+ * Return.......: bool - true if the next token has to be a value, at the start or after an operator
+ * Description..: Tells a - or / that starts a value apart from one that subtracts or divides
+ */
+func expectsValue(tokens []expressionToken) bool {
+	if len(tokens) == 0 {
+		return true
+	}
+
+	last := tokens[len(tokens)-1]
+	return last.kind == operatorToken && last.value != ")"
+}
+
+/**
+ * Name.........: peekOperator
+ * Return.......: string - the operator at the current position, empty if there is none
+ * Description..: Looks at the next token without moving past it
+ */
+func (self *expressionParser) peekOperator() string {
+	if self.pos < len(self.tokens) && self.tokens[self.pos].kind == operatorToken {
+		return self.tokens[self.pos].value
+	}
+
+	return ""
+}
+
+/**
+ * Name.........: text
+ * Parameters...: first (int) - the index of the first token
+ * Return.......: string - the expression from the first token to the last token read
+ * Description..: Gets the source of a part of the expression
+ */
+func (self *expressionParser) text(first int) string {
+	return Helpers.Trim(self.source[self.tokens[first].start:self.tokens[self.pos-1].end])
+}
+
+/**
+ * Name.........: parseOr
+ * Return.......: DataTypes.Expression
+ *                Errors.Error - any errors
+ * Description..: Parses operands joined by ||
+ */
+func (self *expressionParser) parseOr() (DataTypes.Expression, Errors.Error) {
+	first := self.pos
+
+	lhs, err := self.parseAnd()
+	for !err.HasError() && self.peekOperator() == Operators.Logical.Or {
+		self.pos++
+
+		rhs := DataTypes.Expression(nil)
+		rhs, err = self.parseAnd()
+		lhs = DataTypes.BinaryExpression{Operator: Operators.Logical.Or, Left: lhs, Right: rhs, Text: self.text(first)}
+	}
+
+	return lhs, err
+}
+
+/**
+ * Name.........: parseAnd
+ * Return.......: DataTypes.Expression
+ *                Errors.Error - any errors
+ * Description..: Parses operands joined by &&
+ */
+func (self *expressionParser) parseAnd() (DataTypes.Expression, Errors.Error) {
+	first := self.pos
+
+	lhs, err := self.parseNot()
+	for !err.HasError() && self.peekOperator() == Operators.Logical.And {
+		self.pos++
+
+		rhs := DataTypes.Expression(nil)
+		rhs, err = self.parseNot()
+		lhs = DataTypes.BinaryExpression{Operator: Operators.Logical.And, Left: lhs, Right: rhs, Text: self.text(first)}
+	}
+
+	return lhs, err
+}
+
+/**
+ * Name.........: parseNot
+ * Return.......: DataTypes.Expression
+ *                Errors.Error - any errors
+ * Description..: Parses a comparison, negated by any number of ! or not
+ */
+func (self *expressionParser) parseNot() (DataTypes.Expression, Errors.Error) {
+	if self.peekOperator() != Operators.Logical.Not {
+		return self.parseComparison()
+	}
+
+	first := self.pos
+	self.pos++
+
+	operand, err := self.parseNot()
+	if err.HasError() {
+		return nil, err
+	}
+
+	return DataTypes.NotExpression{Operand: operand, Text: self.text(first)}, Errors.None()
+}
+
+/**
+ * Name.........: parseComparison
+ * Return.......: DataTypes.Expression
+ *                Errors.Error - any errors
+ * Description..: Parses a sum, or two sums compared with a comparison or text operator
+ */
+func (self *expressionParser) parseComparison() (DataTypes.Expression, Errors.Error) {
+	first := self.pos
+
+	lhs, err := self.parseSum()
+	if err.HasError() {
+		return nil, err
+	}
+
+	operator := self.peekOperator()
+	if !Operators.IsComparisonOperator(operator) && !Operators.IsTextOperator(operator) {
+		return lhs, Errors.None()
+	}
+	self.pos++
+
+	rhs, err := self.parseSum()
+	if err.HasError() {
+		return nil, err
+	}
+
+	return DataTypes.BinaryExpression{Operator: operator, Left: lhs, Right: rhs, Text: self.text(first)}, Errors.None()
+}
+
+/**
+ * Name.........: parseSum
+ * Return.......: DataTypes.Expression
+ *                Errors.Error - any errors
+ * Description..: Parses products joined by + or -
+ */
+func (self *expressionParser) parseSum() (DataTypes.Expression, Errors.Error) {
+	first := self.pos
+
+	lhs, err := self.parseProduct()
+	for !err.HasError() && Operators.IsAdditiveOperator(self.peekOperator()) {
+		operator := self.peekOperator()
+		self.pos++
+
+		rhs := DataTypes.Expression(nil)
+		rhs, err = self.parseProduct()
+		lhs = DataTypes.BinaryExpression{Operator: operator, Left: lhs, Right: rhs, Text: self.text(first)}
+	}
+
+	return lhs, err
+}
+
+/**
+ * Name.........: parseProduct
+ * Return.......: DataTypes.Expression
+ *                Errors.Error - any errors
+ * Description..: Parses operands joined by *, / or %
+ */
+func (self *expressionParser) parseProduct() (DataTypes.Expression, Errors.Error) {
+	first := self.pos
+
+	lhs, err := self.parseOperand()
+	for !err.HasError() && Operators.IsArithmeticOperator(self.peekOperator()) && !Operators.IsAdditiveOperator(self.peekOperator()) {
+		operator := self.peekOperator()
+		self.pos++
+
+		rhs := DataTypes.Expression(nil)
+		rhs, err = self.parseOperand()
+		lhs = DataTypes.BinaryExpression{Operator: operator, Left: lhs, Right: rhs, Text: self.text(first)}
+	}
+
+	return lhs, err
+}
+
+/**
+ * Name.........: parseOperand
+ * Return.......: DataTypes.Expression
+ *                Errors.Error - any errors
+ * Description..: Parses an expression in parenthesis, or the words and string literals up to the next operator
+ */
+func (self *expressionParser) parseOperand() (DataTypes.Expression, Errors.Error) {
+	if self.pos >= len(self.tokens) {
+		return nil, Errors.NewFatal("Expected a value at the end of ", Helpers.Trim(self.source))
+	}
+
+	// Parenthesis group a whole expression
+	if self.peekOperator() == "(" {
+		self.pos++
+
+		expression, err := self.parseOr()
+		if err.HasError() {
+			return nil, err
+		}
+
+		if self.peekOperator() != ")" {
+			return nil, Errors.NewFatal("Missing ) in ", Helpers.Trim(self.source))
+		}
+		self.pos++
+
+		return expression, Errors.None()
+	}
+
+	first := self.pos
+	depth := 0
+
+	for self.pos < len(self.tokens) {
+		token := self.tokens[self.pos]
+
+		// Parenthesis after a word belong to the operand, like the arguments of a function
+		if token.kind == operatorToken && token.value == "(" && self.pos > first {
+			depth++
+		} else if token.kind == operatorToken && token.value == ")" && depth > 0 {
+			depth--
+		} else if token.kind == operatorToken && depth == 0 {
+			break
+		}
+
+		self.pos++
+	}
+
+	if self.pos == first {
+		return nil, Errors.NewFatal("Expected a value before ", self.tokens[self.pos].value, " in ", Helpers.Trim(self.source))
+	}
+
+	if depth > 0 {
+		return nil, Errors.NewFatal("Missing ) in ", Helpers.Trim(self.source))
+	}
+
+	return DataTypes.OperandExpression{Text: self.text(first)}, Errors.None()
+}
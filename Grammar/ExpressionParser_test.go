@@ -0,0 +1,77 @@
+package Grammar
+
+import (
+	"daphne/DataTypes"
+	"testing"
+)
+
+/**
+ * Name.........: formatExpression
+ * Parameters...: expression (DataTypes.Expression) - a parsed expression
+ * Return.......: string - the tree with parenthesis around every operator, like ((a && b) || c)
+ * Description..: Writes a parsed expression in a way that shows how it was grouped
+ */
+func formatExpression(expression DataTypes.Expression) string {
+	switch expr := expression.(type) {
+	case DataTypes.NotExpression:
+		return "!" + formatExpression(expr.Operand)
+	case DataTypes.BinaryExpression:
+		return "(" + formatExpression(expr.Left) + " " + expr.Operator + " " + formatExpression(expr.Right) + ")"
+	}
+
+	return expression.Source()
+}
+
+func TestParseExpressionPrecedence(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{"a", "a"},
+		{"a || b && c", "(a || (b && c))"},
+		{"a && b || c", "((a && b) || c)"},
+		{"a && b && c", "((a && b) && c)"},
+		{"a || b || c", "((a || b) || c)"},
+		{"(a || b) && c", "((a || b) && c)"},
+		{"a and b or c", "((a && b) || c)"},
+		{"!a && b", "(!a && b)"},
+		{"!(a && b)", "!(a && b)"},
+		{"not a || not b", "(!a || !b)"},
+		{"!!a", "!!a"},
+		{"a == 1 || b != 2 && c", "((a == 1) || ((b != 2) && c))"},
+		{"!a == b", "!(a == b)"},
+		{"\"go\" in page.tags && page.title startswith \"Ho\"", "((\"go\" in page.tags) && (page.title startswith \"Ho\"))"},
+		{"page.title endswith \"e\" || page.title contains \"om\"", "((page.title endswith \"e\") || (page.title contains \"om\"))"},
+		{"((a))", "a"},
+		{"(a || (b && (c || d)))", "(a || (b && (c || d)))"},
+	}
+
+	for _, test := range tests {
+		expression, err := ParseExpression(test.source)
+		if err.HasError() {
+			t.Errorf("parsing %q: %s", test.source, err.Msg)
+			continue
+		}
+
+		if result := formatExpression(expression); result != test.expected {
+			t.Errorf("parsing %q\n got: %s\nwant: %s", test.source, result, test.expected)
+		}
+	}
+}
+
+func TestParseExpressionErrors(t *testing.T) {
+	for _, source := range []string{
+		"",
+		"a &&",
+		"|| a",
+		"(a || b",
+		"a || b)",
+		"!",
+		"a == == b",
+		"()",
+	} {
+		if _, err := ParseExpression(source); !err.HasError() {
+			t.Errorf("expected parsing %q to fail", source)
+		}
+	}
+}
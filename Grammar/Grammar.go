@@ -3,23 +3,22 @@ package Grammar
 /**
  * Curly Braces are repetition (0+), square brackets represent optional part of a rule
  *
- * Expression := (Term ConditionalOperator Term) | Term
- * Term := integer | decimal | identifier | String literall
- *
- *
+ * Conditions are parsed by ParseExpression (ExpressionParser.go)
  */
 import (
 	"daphne/DataTypes"
-	"daphne/Grammar/Operators"
+	"daphne/Errors"
 	"daphne/Helpers"
 	"daphne/State"
+	"regexp"
+	"strings"
 )
 
 /**
  * Returns true if something is a keyword
  */
 func IsKeyword(inp string) bool {
-	return inp == "if" || inp == "endif" || inp == "else" || inp == "include"
+	return inp == "if" || inp == "else" || inp == "elif" || inp == "end" || inp == "switch" || inp == "case" || inp == "default" || inp == "foreach" || inp == "break" || inp == "continue" || inp == "set" || inp == "capture" || inp == "include" || inp == "extends" || inp == "block" || inp == "macro" || inp == "import" || inp == "raw"
 }
 
 /**
@@ -50,183 +49,6 @@ func IsStringLit(inp string) bool {
 	return true
 }
 
-/**
- * Short hand, inline for the IsConditional
- */
-func IsCondit(line string) bool {
-	results, _, _, _ := IsConditional(line)
-	return results
-}
-
-/**
- * Determines if something is a conditional expression
- */
-func IsConditional(line string) (bool, string, string, string) {
-	state := 0
-
-	if len(line) < 2 {
-		return false, "", "", ""
-	}
-
-	lhs := ""
-	operator := ""
-	rhs := ""
-
-	waitingFor := DataTypes.StringStack{}
-	returnToState := 0
-
-	lastLetter := ""
-
-	for _, c := range line {
-		letter := string(c)
-
-		switch state {
-		case 0:
-			if letter == "'" || letter == "\"" {
-				state = 1
-				waitingFor.Push(letter)
-			} else if letter == "(" {
-				state = 1
-				waitingFor.Push(")")
-			} else if Operators.IsComparisonOperator(lastLetter+letter) || Operators.IsComparisonOperator(letter) {
-				lhs = lhs[:len(lhs)-2]
-				operator = lastLetter + letter
-				state = 2
-			}
-
-			if state != 2 {
-				lhs = lhs + letter
-			}
-
-			returnToState = 0
-
-		// Waiting to get out of something
-		case 1:
-			if letter == waitingFor.Peek() && lastLetter != "\\" {
-				_, length := waitingFor.Pop()
-				if length <= 0 {
-					state = returnToState
-				}
-			} else if letter == "'" || letter == "\"" {
-				waitingFor.Push(letter)
-			} else if letter == "(" {
-				waitingFor.Push(")")
-			}
-
-			if returnToState == 0 {
-				lhs = lhs + letter
-			} else {
-				rhs = rhs + letter
-			}
-
-		// Reading the rhs
-		case 2:
-			if letter == "'" || letter == "\"" {
-				state = 1
-				waitingFor.Push(letter)
-			} else if letter == "(" {
-				state = 1
-				waitingFor.Push(")")
-			}
-			rhs = rhs + letter
-			returnToState = 2
-
-		}
-
-		lastLetter = letter
-	}
-
-	return (state == 2 && waitingFor.Length() == 0), Helpers.Strip(lhs), operator, Helpers.Strip(rhs)
-}
-
-/**
- * Short hand, inline for the IsConditional
- */
-func IsLogic(line string) bool {
-	results, _, _, _ := IsLogicalCondition(line)
-	return results
-}
-
-/**
- * Returns true if a conditional statement is compounded
- */
-func IsLogicalCondition(condition string) (bool, string, string, string) {
-	state := 0
-	if len(condition) < 2 {
-		return false, "", "", ""
-	}
-
-	lhs := ""
-	operator := ""
-	rhs := ""
-
-	waitingFor := DataTypes.StringStack{}
-	returnToState := 0
-
-	lastLetter := ""
-
-	for _, c := range condition {
-		letter := string(c)
-
-		switch state {
-		case 0:
-			if letter == "'" || letter == "\"" {
-				state = 1
-				waitingFor.Push(letter)
-			} else if letter == "(" {
-				state = 1
-				waitingFor.Push(")")
-			} else if Operators.IsLogicalOperator(lastLetter + letter) {
-				lhs = lhs[:len(lhs)-2]
-				operator = lastLetter + letter
-				state = 2
-			}
-
-			if state != 2 {
-				lhs = lhs + letter
-			}
-
-			returnToState = 0
-
-		// Waiting to get out of something
-		case 1:
-			if letter == waitingFor.Peek() && lastLetter != "\\" {
-				_, length := waitingFor.Pop()
-				if length <= 0 {
-					state = returnToState
-				}
-			} else if letter == "'" || letter == "\"" {
-				waitingFor.Push(letter)
-			} else if letter == "(" {
-				waitingFor.Push(")")
-			}
-
-			if returnToState == 0 {
-				lhs = lhs + letter
-			} else {
-				rhs = rhs + letter
-			}
-
-		// Reading the rhs
-		case 2:
-			if letter == "'" || letter == "\"" {
-				state = 1
-				waitingFor.Push(letter)
-			} else if letter == "(" {
-				state = 1
-				waitingFor.Push(")")
-			}
-			rhs = rhs + letter
-			returnToState = 2
-
-		}
-
-		lastLetter = letter
-	}
-
-	return (state == 2 && waitingFor.Length() == 0), Helpers.Strip(lhs), operator, Helpers.Strip(rhs)
-}
-
 /**
  * Returns true if a ternary statement
  */
@@ -339,293 +161,334 @@ func IsTernary(line string) (bool, string, string, string) {
 	return result, Helpers.Strip(condition), Helpers.Trim(Helpers.StripParens(ifTrue)), Helpers.Trim(Helpers.StripParens(ifFalse))
 }
 
-var validFunctions = []string{"post_asset"}
-
 /**
- * Name.........: IsSpecialFunction
- * Parameters...: line (string) - the line to check if it is a special function
- * Return.......: boolean, string, string
- * Description..: determines if a line is a special function or not
+ * Name.........: SplitPipeline
+ * Parameters...: expression (string) - the inside of a print
+ * Return.......: []string - the value, followed by every filter
+ * Description..: Splits a print at every | that is not inside of quotes or parenthesis, and is not part of ||
  */
-func IsSpecialFunction(line string) (bool, string, string) {
-	funcName := ""
-	funcParams := ""
+func SplitPipeline(expression string) []string {
+	parts := []string{}
 
-	sm := State.NewStateMachine(0)
+	start := 0
+	quote := byte(0)
+	depth := 0
 
-	// State 0 - looking for opening parens
-	sm.AddState(0, func(inp string, lastInp string, stack *DataTypes.DataStack) int {
-		state := 0
+	for i := 0; i < len(expression); i++ {
+		c := expression[i]
 
-		if inp == "(" {
-			state = 1
-		} else {
-			funcName = funcName + inp
-		}
-
-		return state
-	})
-
-	// State 1 - Looking for closing parenthesis
-	sm.AddState(1, func(inp string, lastInp string, stack *DataTypes.DataStack) int {
-		state := 1
-
-		if inp == ")" {
-			state = 2
-		} else {
-			funcParams = funcParams + inp
-		}
-
-		return state
-	})
-
-	// State 2 - making sure nothing else happens
-	sm.AddFinalState(2, func(inp string, lastInp string, stack *DataTypes.DataStack) int {
-		if inp != "" {
-			return 0
+		if quote != 0 {
+			if c == quote && expression[i-1] != '\\' {
+				quote = 0
+			}
+			continue
 		}
-		return 2
-	})
-
-	// Run the state machine
-	result, err := sm.Run(line)
-	err.Handle()
 
-	// Validate function name
-	funcIsValid := false
-	for _, name := range validFunctions {
-		if name == funcName {
-			funcIsValid = true
-			break
+		switch c {
+		case '"', '\'':
+			quote = c
+		case '(', '[':
+			depth++
+		case ')', ']':
+			depth--
+		case '|':
+			if i+1 < len(expression) && expression[i+1] == '|' {
+				i++ // Skip over ||
+			} else if depth == 0 {
+				parts = append(parts, Helpers.Trim(expression[start:i]))
+				start = i + 1
+			}
 		}
 	}
 
-	return result && funcIsValid, funcName, Helpers.Trim(funcParams)
-}
-
-// Shorthand
-func IsSpecialFunc(line string) bool {
-	result, _, _ := IsSpecialFunction(line)
-
-	return result
+	return append(parts, Helpers.Trim(expression[start:]))
 }
 
 /**
- * Name.........: FindInlinePrints
- * Parameters...: line (string) - the line to search inline commands for
- * Return.......: []string - an array of all the commands found
- * Description..: Finds inline commands in a line
+ * Name.........: SplitArguments
+ * Parameters...: args (string) - the arguments
+ * Return.......: []string - every argument
+ * Description..: Splits arguments separated by spaces, anything in quotes or parenthesis stays together
  */
-func FindInlinePrints(line string) []string {
+func SplitArguments(args string) []string {
 	result := []string{}
 
-	state := 0
-	command := ""
-
-	lastLetter := ""
+	current := ""
+	quote := byte(0)
+	depth := 0
 
-	for _, c := range line {
-		letter := string(c)
+	for i := 0; i < len(args); i++ {
+		c := args[i]
 
-		switch state {
-		// Waiting for opening tags
-		case 0:
-			if letter == "{" && lastLetter == "{" {
-				command = "{{"
-				state = 1
+		if quote != 0 {
+			if c == quote && args[i-1] != '\\' {
+				quote = 0
 			}
-
-		// Reading command
-		case 1:
-			// Add to command
-			command = command + letter
-
-			if letter == "}" && lastLetter == "}" {
-				state = 0 // This command has finished
-
-				// Add command to the result
-				result = append(result, command)
-				command = ""
+		} else if c == '"' || c == '\'' {
+			quote = c
+		} else if c == '(' || c == '[' {
+			depth++
+		} else if c == ')' || c == ']' {
+			depth--
+		} else if (c == ' ' || c == '\t') && depth == 0 {
+			if current != "" {
+				result = append(result, current)
 			}
+			current = ""
+			continue
 		}
-		lastLetter = letter
+
+		current = current + string(c)
+	}
+
+	if current != "" {
+		result = append(result, current)
 	}
 
 	return result
 }
 
 /**
- * Name.........: IsSetCommand
- * Parameters...: line (string) - the line to search
- * Return.......: bool - If it is a set command
- *                string - variable to set
- *                string - value to set
- * Description..: Determines if a line is a set command
+ * Name.........: SplitNamedArguments
+ * Parameters...: args (string) - the arguments, name=value separated by spaces
+ * Return.......: map[string]string - the value of every argument, by name
+ *                Errors.Error - any errors
+ * Description..: Splits name=value arguments, a value goes on until the next name= that is not inside of
+ *                quotes or parenthesis, so it can have spaces in it
  */
-func IsSetCommand(line string) (bool, string, string) {
-	line = Helpers.Strip(line)
+func SplitNamedArguments(args string) (map[string]string, Errors.Error) {
+	result := make(map[string]string)
 
-	if len(line) < 4 {
-		return false, "", ""
-	}
+	name := ""
+	start := 0
+	quote := byte(0)
+	depth := 0
 
-	// Make sure it is the only thing on the line
-	if line[:2] == "{%" && line[len(line)-2:] == "%}" {
-		// Check if include
-		line = Helpers.Trim(line[2 : len(line)-2])
+	for i := 0; i < len(args); i++ {
+		c := args[i]
 
-		tokens := Helpers.Split(line, " ")
+		if quote != 0 {
+			if c == quote && args[i-1] != '\\' {
+				quote = 0
+			}
+			continue
+		}
 
-		if len(tokens) < 4 {
-			return false, "", ""
+		switch c {
+		case '"', '\'':
+			quote = c
+			continue
+		case '(', '[':
+			depth++
+			continue
+		case ')', ']':
+			depth--
+			continue
 		}
 
-		if tokens[0] == "set" {
-			if tokens[2] == "=" {
-				return true, Helpers.Strip(tokens[1]), Helpers.Join(tokens[3:len(tokens)], " ")
-			}
+		// A name starts a new word, and is followed by a single =
+		if depth > 0 || (i > 0 && args[i-1] != ' ' && args[i-1] != '\t') {
+			continue
 		}
-	}
 
-	return false, "", ""
-}
+		end := i
+		for end < len(args) && isNameCharacter(args[end], end == i) {
+			end++
+		}
+		if end == i || end >= len(args) || args[end] != '=' || (end+1 < len(args) && args[end+1] == '=') {
+			continue
+		}
 
-/**
- * Name.........: IsIncludeStatement
- * Parameters...: line (string) - the line to search
- * Return.......: bool
- *                string - the file to include
- * Description..: Determines if a line is an include statement
- */
-func IsIncludeStatement(line string) (bool, string) {
-	line = Helpers.Strip(line)
+		if name == "" && Helpers.Trim(args[:i]) != "" {
+			return nil, Errors.NewFatal("Invalid argument ", Helpers.Trim(args[:i]), ", expected name=value")
+		} else if name != "" {
+			result[name] = Helpers.Trim(args[start:i])
+		}
 
-	if len(line) < 4 {
-		return false, "" // Not long enough
+		name = Helpers.ToLower(args[i:end])
+		if _, exists := result[name]; exists {
+			return nil, Errors.NewFatal("The argument ", name, " is given twice")
+		}
+		start = end + 1
+		i = end
 	}
 
-	// Make sure it is the only thing on the line
-	if line[:2] == "{%" && line[len(line)-2:] == "%}" {
-		// Check if include
-		line = Helpers.Trim(line[2 : len(line)-2])
-
-		tokens := Helpers.Split(line, " ")
-
-		if len(tokens) < 2 {
-			return false, ""
-		}
+	if name == "" && Helpers.Trim(args) != "" {
+		return nil, Errors.NewFatal("Invalid argument ", Helpers.Trim(args), ", expected name=value")
+	} else if name != "" {
+		result[name] = Helpers.Trim(args[start:])
+	}
 
-		if tokens[0] == "include" {
-			return true, Helpers.Strip(tokens[1])
+	for key, value := range result {
+		if value == "" {
+			return nil, Errors.NewFatal("Missing value for the argument ", key)
 		}
-
-		return false, ""
 	}
 
-	return false, ""
+	return result, Errors.None()
+}
+
+/**
+ * Name.........: isNameCharacter
+ * Parameters...: c (byte) - the character
+ *                first (bool) - true if it is the first character of the name
+ * Return.......: bool
+ * Description..: True if the character can be part of a name, names start with a letter or _
+ */
+func isNameCharacter(c byte, first bool) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_' || (!first && c >= '0' && c <= '9')
 }
 
+var CallNameRegex, _ = regexp.Compile("^[A-Za-z_][A-Za-z0-9_]*(\\.[A-Za-z_][A-Za-z0-9_]*)*$")
+
 /**
- * Name.........: StartsAMultilineCommand
- * Parameters...: line (string) - the line to search
- * Return.......: boolean
- * Description..: Determines if a line is the start of a multiline command
+ * Name.........: IsCall
+ * Parameters...: expression (string) - the expression to check
+ * Return.......: bool - true if the expression is a call
+ *                string - the name of what is called
+ *                []string - the arguments
+ * Description..: Determines if an expression is a call like name(argument, argument), the name can have dots in it
  */
-func StartsMultilineCommand(line string) bool {
-	line = Helpers.Strip(line) // Strip everything since a command can only be on a line by itself
+func IsCall(expression string) (bool, string, []string) {
+	expression = Helpers.Trim(expression)
 
-	if len(line) < 4 {
-		return false // Not enoug characters
+	open := strings.Index(expression, "(")
+	if open <= 0 || expression[len(expression)-1] != ')' {
+		return false, "", nil
 	}
 
-	// Has a command if the first and last two characters are tags
-	if line[:2] == "{%" && line[len(line)-2:] == "%}" {
-		isSet, _, _ := IsSetCommand(line)
-		return true && !isSet
+	// No space between the name and the parenthesis, so text like "see (below)" is not a call
+	name := expression[:open]
+	if !CallNameRegex.MatchString(name) {
+		return false, "", nil
 	}
-	return false
+
+	// The parenthesis that open the arguments have to be the ones that close at the end
+	args := expression[open+1 : len(expression)-1]
+	if !parensMatch(args) {
+		return false, "", nil
+	}
+
+	return true, name, DataTypes.SplitList(args)
 }
 
 /**
- * Name.........: StartsElseCommand
- * Parameters...: line (string) - the line to search
- * Return.......: boolean
- * Description..: Determines if a line is an else statement
+ * Name.........: SplitCall
+ * Parameters...: expression (string) - the expression to split
+ * Return.......: string - the call at the start of the expression, empty if it does not start with one
+ *                string - everything after the call, like .title in first(site.posts).title
+ * Description..: Finds the call an expression starts with, a call on its own has nothing after it
  */
-func StartsElseCommand(line string) bool {
-	line = Helpers.Strip(line)
+func SplitCall(expression string) (string, string) {
+	expression = Helpers.Trim(expression)
 
-	if len(line) < 10 {
-		return false
+	open := strings.Index(expression, "(")
+	if open <= 0 || !CallNameRegex.MatchString(expression[:open]) {
+		return "", ""
 	}
 
-	if line[:2] == "{%" && line[len(line)-2:] == "%}" {
-		line = Helpers.Trim(line[2 : len(line)-2])
-
-		if line == "else" {
-			return true
+	// Every parenthesis that is opened has to be closed before the call ends
+	for end := open + 1; end < len(expression); end++ {
+		if expression[end] == ')' && parensMatch(expression[open:end+1]) {
+			if isCall, _, _ := IsCall(expression[:end+1]); isCall {
+				return expression[:end+1], expression[end+1:]
+			}
 		}
 	}
 
-	return false
+	return "", ""
 }
 
 /**
- * Name.........: EndsMultilineCommand
- * Parameters...: line (string) - the line to search
- * Return.......: boolean
- * Description..: Determines if a line is an end statement
+ * Name.........: IsRange
+ * Parameters...: expression (string) - the expression to check
+ * Return.......: bool - true if the expression is a range
+ *                string - the first number
+ *                string - the last number
+ * Description..: Determines if an expression is a range like 1..5, either end can be any value
  */
-func EndsMultilineCommand(line string) bool {
-	line = Helpers.Strip(line)
+func IsRange(expression string) (bool, string, string) {
+	quote := byte(0)
+	depth := 0
 
-	if len(line) < 9 {
-		return false // Not long enough
+	for i := 0; i+1 < len(expression); i++ {
+		c := expression[i]
+
+		if quote != 0 {
+			if c == quote && expression[i-1] != '\\' {
+				quote = 0
+			}
+		} else if c == '"' || c == '\'' {
+			quote = c
+		} else if c == '(' || c == '[' {
+			depth++
+		} else if c == ')' || c == ']' {
+			depth--
+		} else if c == '.' && expression[i+1] == '.' && depth == 0 {
+			start := Helpers.Trim(expression[:i])
+			end := Helpers.Trim(expression[i+2:])
+
+			return start != "" && end != "", start, end
+		}
 	}
 
-	if line[:2] == "{%" && line[len(line)-2:] == "%}" {
-		line = Helpers.Trim(line[2 : len(line)-2]) // Remove tags
+	return false, "", ""
+}
 
-		tokens := Helpers.Split(line, " ")
+/**
+ * Name.........: parensMatch
+ * Parameters...: str (string) - the string to check
+ * Return.......: bool
+ * Description..: True if every parenthesis outside of quotes is closed, and none are closed before they are opened
+ */
+func parensMatch(str string) bool {
+	quote := byte(0)
+	depth := 0
 
-		if len(tokens) < 2 {
-			return false
-		}
+	for i := 0; i < len(str); i++ {
+		c := str[i]
 
-		if tokens[0] == "end" {
-			return true
+		if quote != 0 {
+			if c == quote && str[i-1] != '\\' {
+				quote = 0
+			}
+		} else if c == '"' || c == '\'' {
+			quote = c
+		} else if c == '(' {
+			depth++
+		} else if c == ')' {
+			depth--
+			if depth < 0 {
+				return false
+			}
 		}
 	}
 
-	return false
+	return depth == 0 && quote == 0
 }
 
 /**
- * Name.........: EndsMultilineCommand
- * Parameters...: line (string) - the line to search
- * Return.......: boolean
- * Description..: Determines if a line is an end statement
+ * Name.........: SplitNamedArgument
+ * Parameters...: arg (string) - an argument
+ * Return.......: bool - true if the argument is named
+ *                string - the name
+ *                string - the value
+ * Description..: Splits an argument written as name=value
  */
-func WhatDoesEndCommandEnd(line string) string {
-	line = Helpers.Strip(line)
+func SplitNamedArgument(arg string) (bool, string, string) {
+	arg = Helpers.Trim(arg)
 
-	if len(line) < 9 {
-		return "" // Not long enough
+	end := 0
+	for end < len(arg) && isNameCharacter(arg[end], end == 0) {
+		end++
 	}
 
-	if line[:2] == "{%" && line[len(line)-2:] == "%}" {
-		line = Helpers.Trim(line[2 : len(line)-2]) // Remove tags
-
-		tokens := Helpers.Split(line, " ")
+	name := arg[:end]
+	rest := Helpers.Trim(arg[end:])
 
-		if len(tokens) < 2 {
-			return ""
-		}
-
-		if tokens[0] == "end" {
-			return Helpers.Trim(tokens[1])
-		}
+	if name == "" || len(rest) < 2 || rest[0] != '=' || rest[1] == '=' {
+		return false, "", arg
 	}
 
-	return ""
+	return true, Helpers.ToLower(name), Helpers.Trim(rest[1:])
 }
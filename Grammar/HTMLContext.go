@@ -0,0 +1,280 @@
+package Grammar
+
+import (
+	"daphne/DataTypes"
+	"daphne/Helpers"
+	"strings"
+)
+
+// Attributes that hold a URL
+var urlAttributes = []string{"href", "src", "action", "formaction", "cite", "poster", "background", "data", "srcset", "xlink:href"}
+
+/**
+ * Follows the HTML of a template to know where each print is
+ */
+type htmlScanner struct {
+	inTag       bool
+	readingName bool // Reading the name of the tag
+	tagName     string
+	word        string // The last word read inside of a tag, the name of the attribute if = follows
+	wordEnded   bool
+	attribute   string // The attribute whose value is being read
+	expectValue bool   // After the = of an attribute
+	inValue     bool
+	valueLength int
+	quote       byte // The quote around the value of the attribute, 0 if there is none
+	comment     bool
+	rawText     string // script or style while inside of one
+	script      scriptScanner
+}
+
+/**
+ * Follows the JavaScript of a script element or an on* attribute, to know if a print is inside of a string
+ */
+type scriptScanner struct {
+	quote   byte   // The quote the string being read was started with, 0 outside of strings
+	comment string // // or /* while inside of a comment
+}
+
+/**
+ * Name.........: scan
+ * Parameters...: text (string) - the text of the template up to the next tag
+ * Description..: Reads the text and keeps track of where in the HTML it ends
+ */
+func (self *htmlScanner) scan(text string) {
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		space := c == ' ' || c == '\t' || c == '\n' || c == '\r'
+
+		switch {
+		case self.comment:
+			if strings.HasPrefix(text[i:], "-->") {
+				self.comment = false
+				i += 2
+			}
+
+		case self.rawText != "":
+			if strings.HasPrefix(Helpers.ToLower(text[i:]), "</"+self.rawText) {
+				self.rawText = ""
+				self.startTag()
+			} else if self.rawText == "script" {
+				i = self.script.scan(text, i)
+			}
+
+		case !self.inTag:
+			if strings.HasPrefix(text[i:], "<!--") {
+				self.comment = true
+				i += 3
+			} else if c == '<' && i+1 < len(text) && (isNameCharacter(text[i+1], true) || text[i+1] == '/') {
+				self.startTag()
+			}
+
+		case self.inValue:
+			if (self.quote != 0 && c == self.quote) || (self.quote == 0 && space) {
+				self.inValue = false
+				self.attribute = ""
+			} else if self.quote == 0 && c == '>' {
+				self.endTag()
+			} else {
+				if isScriptAttribute(self.attribute) {
+					i = self.script.scan(text, i)
+				}
+				self.valueLength++
+			}
+
+		case self.expectValue:
+			if space {
+				continue
+			}
+
+			self.startValue()
+
+			if c == '"' || c == '\'' {
+				self.quote = c
+			} else if c == '>' {
+				self.endTag()
+			} else {
+				if isScriptAttribute(self.attribute) {
+					i = self.script.scan(text, i)
+				}
+				self.valueLength++
+			}
+
+		default:
+			if self.readingName && (isNameCharacter(c, false) || c == '/' || c == '-' || c == ':') {
+				self.tagName = self.tagName + Helpers.ToLower(string(c))
+				continue
+			}
+			self.readingName = false
+
+			if c == '>' {
+				self.endTag()
+			} else if c == '=' {
+				self.attribute = Helpers.ToLower(self.word)
+				self.expectValue = true
+				self.word = ""
+			} else if space || c == '/' {
+				self.wordEnded = true
+			} else {
+				if self.wordEnded {
+					self.word = ""
+					self.wordEnded = false
+				}
+				self.word = self.word + string(c)
+			}
+		}
+	}
+}
+
+/**
+ * Name.........: startTag
+ * Description..: Starts reading an HTML tag
+ */
+func (self *htmlScanner) startTag() {
+	self.inTag = true
+	self.readingName = true
+	self.tagName = ""
+	self.word = ""
+	self.attribute = ""
+}
+
+/**
+ * Name.........: startValue
+ * Description..: Starts reading the value of an attribute
+ */
+func (self *htmlScanner) startValue() {
+	self.expectValue = false
+	self.inValue = true
+	self.valueLength = 0
+	self.quote = 0
+	self.script = scriptScanner{}
+}
+
+/**
+ * Name.........: endTag
+ * Description..: Stops reading an HTML tag, the text of script and style elements is not HTML
+ */
+func (self *htmlScanner) endTag() {
+	self.inTag = false
+	self.inValue = false
+	self.expectValue = false
+	self.attribute = ""
+
+	if self.tagName == "script" || self.tagName == "style" {
+		self.rawText = self.tagName
+		self.script = scriptScanner{}
+	}
+}
+
+/**
+ * Name.........: print
+ * Return.......: DataTypes.HTMLContext - the context of a print at the current position
+ *                DataTypes.TagPosition - whether the print is inside of a tag, and if it is quoted
+ * Description..: Gets the context of a print, and counts the print as part of the value it is in
+ */
+func (self *htmlScanner) print() (DataTypes.HTMLContext, DataTypes.TagPosition) {
+	if self.rawText == "style" {
+		return DataTypes.StyleContext, DataTypes.OutsideTag
+	} else if self.rawText != "" {
+		return self.script.context(), DataTypes.OutsideTag
+	} else if !self.inTag {
+		return DataTypes.TextContext, DataTypes.OutsideTag
+	}
+
+	// A print right after the = is a value without quotes
+	if self.expectValue {
+		self.startValue()
+	}
+
+	if !self.inValue {
+		return DataTypes.AttributeContext, DataTypes.UnquotedValue
+	}
+
+	position := DataTypes.QuotedValue
+	if self.quote == 0 {
+		position = DataTypes.UnquotedValue
+	}
+
+	context := DataTypes.AttributeContext
+	if isScriptAttribute(self.attribute) {
+		context = self.script.context()
+	} else if self.attribute == "style" {
+		context = DataTypes.StyleContext
+	} else if self.valueLength == 0 {
+		for _, attribute := range urlAttributes {
+			if self.attribute == attribute {
+				context = DataTypes.URLContext
+			}
+		}
+	}
+	self.valueLength++
+
+	return context, position
+}
+
+/**
+ * Name.........: isScriptAttribute
+ * Parameters...: attribute (string) - the name of the attribute
+ * Return.......: bool
+ * Description..: True if the value of the attribute is JavaScript, like onclick
+ */
+func isScriptAttribute(attribute string) bool {
+	return len(attribute) > 2 && attribute[:2] == "on"
+}
+
+/**
+ * Name.........: scan
+ * Parameters...: text (string) - the text being read
+ *                i (int) - the position of the character to read
+ * Return.......: int - the position of the last character read
+ * Description..: Reads a character of JavaScript, and the one after it if it is part of the same thing
+ */
+func (self *scriptScanner) scan(text string, i int) int {
+	c := text[i]
+	next := byte(0)
+	if i+1 < len(text) {
+		next = text[i+1]
+	}
+
+	switch {
+	case self.comment == "//":
+		if c == '\n' {
+			self.comment = ""
+		}
+
+	case self.comment == "/*":
+		if c == '*' && next == '/' {
+			self.comment = ""
+			return i + 1
+		}
+
+	case self.quote != 0:
+		if c == '\\' {
+			return i + 1 // Skip what is escaped
+		} else if c == self.quote || (c == '\n' && self.quote != '`') {
+			self.quote = 0
+		}
+
+	case c == '/' && (next == '/' || next == '*'):
+		self.comment = string([]byte{c, next})
+		return i + 1
+
+	case c == '"' || c == '\'' || c == '`':
+		self.quote = c
+	}
+
+	return i
+}
+
+/**
+ * Name.........: context
+ * Return.......: DataTypes.HTMLContext
+ * Description..: The context of a print at the current position of the script
+ */
+func (self *scriptScanner) context() DataTypes.HTMLContext {
+	if self.quote != 0 {
+		return DataTypes.ScriptStringContext
+	}
+
+	return DataTypes.ScriptContext
+}
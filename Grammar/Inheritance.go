@@ -0,0 +1,80 @@
+package Grammar
+
+import (
+	"daphne/DataTypes"
+	"daphne/Errors"
+	"daphne/Helpers"
+)
+
+/**
+ * Name.........: FindExtends
+ * Parameters...: nodes ([]DataTypes.Node) - a parsed template
+ *                syntax (DataTypes.Syntax) - the delimiters, for error messages
+ * Return.......: *DataTypes.ExtendsNode - the extends command, nil if the template does not extend another one
+ *                Errors.Error - any errors
+ * Description..: Finds the {% extends %} of a template, it has to be outside of everything else
+ */
+func FindExtends(nodes []DataTypes.Node, syntax DataTypes.Syntax) (*DataTypes.ExtendsNode, Errors.Error) {
+	var extends *DataTypes.ExtendsNode
+
+	for _, node := range nodes {
+		if cmd, isExtends := node.(DataTypes.ExtendsNode); isExtends {
+			if extends != nil {
+				return nil, Errors.NewFatal("A template can only extend one template, found a second ", syntax.Tag("extends"), " on line ", Helpers.ToStr(cmd.Line))
+			}
+			extends = &cmd
+		}
+	}
+
+	return extends, Errors.None()
+}
+
+/**
+ * Name.........: CollectBlocks
+ * Parameters...: nodes ([]DataTypes.Node) - a parsed template
+ *                blocks (map[string][]DataTypes.Node) - where to put the body of every block, by name
+ * Return.......: Errors.Error - any errors
+ * Description..: Finds every block in a template, including blocks inside of other commands
+ */
+func CollectBlocks(nodes []DataTypes.Node, blocks map[string][]DataTypes.Node) Errors.Error {
+	for _, node := range nodes {
+		err := Errors.None()
+
+		switch cmd := node.(type) {
+		case DataTypes.BlockNode:
+			if _, exists := blocks[cmd.Name]; exists {
+				return Errors.NewFatal("The block ", cmd.Name, " is defined twice, again on line ", Helpers.ToStr(cmd.Line))
+			}
+			blocks[cmd.Name] = cmd.Body
+			err = CollectBlocks(cmd.Body, blocks)
+
+		case DataTypes.IfNode:
+			for _, branch := range cmd.Branches {
+				if err = CollectBlocks(branch.Body, blocks); err.HasError() {
+					return err
+				}
+			}
+			err = CollectBlocks(cmd.Else, blocks)
+
+		case DataTypes.SwitchNode:
+			for _, branch := range cmd.Cases {
+				if err = CollectBlocks(branch.Body, blocks); err.HasError() {
+					return err
+				}
+			}
+			err = CollectBlocks(cmd.Default, blocks)
+
+		case DataTypes.ForeachNode:
+			err = CollectBlocks(cmd.Body, blocks)
+
+		case DataTypes.CaptureNode:
+			err = CollectBlocks(cmd.Body, blocks)
+		}
+
+		if err.HasError() {
+			return err
+		}
+	}
+
+	return Errors.None()
+}
@@ -0,0 +1,351 @@
+package Grammar
+
+import (
+	"daphne/DataTypes"
+	"daphne/Errors"
+	"daphne/Helpers"
+	"regexp"
+	"strings"
+)
+
+/**
+ * Name.........: Tokenize
+ * Parameters...: source (string) - the template to split into tokens
+ *                syntax (DataTypes.Syntax) - how the template is written
+ * Return.......: []DataTypes.Token - the tokens found
+ *                Errors.Error - any errors
+ * Description..: Splits a template into text, print and tag tokens. Tags can be anywhere in the text,
+ *                a tag that is the only thing on its line takes the whole line with it. Comments are
+ *                removed, and everything between {% raw %} and {% end raw %} is text. A - inside of the
+ *                opening or closing of a tag removes all the whitespace before or after it. The delimiters
+ *                come from the syntax, {% %} is only the default
+ */
+func Tokenize(source string, syntax DataTypes.Syntax) ([]DataTypes.Token, Errors.Error) {
+	tokens := []DataTypes.Token{}
+
+	for _, delimiter := range []string{syntax.TagOpening, syntax.TagClosing, syntax.PrintOpening, syntax.PrintClosing, syntax.CommentOpening, syntax.CommentClosing} {
+		if Helpers.Trim(delimiter) == "" {
+			return tokens, Errors.NewFatal("The opening and closing of commands, prints and comments can not be empty, check compiler.tags in the config")
+		}
+	}
+
+	pos := 0
+	line := 1
+	html := htmlScanner{}
+	trimNext := false // The tag before wants the whitespace after it removed
+
+	for pos < len(source) {
+		start, opening := findOpening(source, pos, syntax)
+		if start < 0 {
+			tokens = appendText(tokens, trimText(source[pos:], trimNext, false), line)
+			break
+		}
+
+		tagLine := line + strings.Count(source[pos:start], "\n")
+
+		token, end, closing := readTag(source, start, opening, syntax)
+		if end < 0 {
+			return tokens, Errors.NewFatal("No closing ", closing, " for the ", opening, " on line ", Helpers.ToStr(tagLine))
+		}
+		token.Line = tagLine
+
+		html.scan(source[pos:start])
+		if token.Type == DataTypes.PrintToken {
+			token.Context, token.Position = html.print()
+		}
+		after := end + len(closing)
+
+		text := source[pos:start]
+		lineStart, lineEnd, alone := findLine(source, pos, start, after)
+
+		if alone && (token.Type != DataTypes.PrintToken || IsSuper(token.Value)) {
+			// Remove the indentation and the line break along with the tag, {{ super }} brings its own
+			token.Standalone = true
+			text = source[pos:lineStart]
+			after = lineEnd + 1
+		} else if alone {
+			// Prints keep the indentation so it can be dropped for multiline values
+			token.Indent = source[lineStart:start]
+			text = source[pos:lineStart]
+		} else if syntax.TrimTagLines && token.Type != DataTypes.PrintToken && onlyTags(source, lineStart, lineEnd, syntax) {
+			// A line of nothing but commands and comments is removed as a whole
+			token.Standalone = true
+			if lineStart >= pos {
+				text = source[pos:lineStart]
+			} else {
+				text = ""
+			}
+			if Helpers.Trim(source[after:lineEnd]) == "" {
+				after = lineEnd + 1
+			}
+		}
+
+		if token.TrimBefore {
+			token.Indent = ""
+		}
+		tokens = appendText(tokens, trimText(text, trimNext, token.TrimBefore), line)
+		trimNext = token.TrimAfter
+
+		if token.Type == DataTypes.TagToken && token.Value == "raw" {
+			// Everything up to the end of the raw is text, even tags
+			rawStart := after
+			if rawStart > len(source) {
+				rawStart = len(source)
+			}
+
+			endRaw, endStart, endEnd := findRawEnd(source, rawStart, syntax)
+			if endStart < 0 {
+				return tokens, Errors.NewFatal("No ", syntax.TagOpening, " end raw ", syntax.TagClosing, " for the raw on line ", Helpers.ToStr(tagLine))
+			}
+
+			after = endEnd
+			if lineStart, lineEnd, alone := findLine(source, rawStart, endStart, endEnd); alone {
+				endStart = lineStart
+				after = lineEnd + 1
+			}
+
+			raw := source[rawStart:endStart]
+			html.scan(raw)
+			tokens = appendText(tokens, trimText(raw, trimNext, endRaw.TrimBefore), line+strings.Count(source[pos:rawStart], "\n"))
+			trimNext = endRaw.TrimAfter
+		} else if token.Type != DataTypes.CommentToken {
+			tokens = append(tokens, token)
+		}
+
+		if after > len(source) {
+			after = len(source)
+		}
+		line = line + strings.Count(source[pos:after], "\n")
+		pos = after
+	}
+
+	return tokens, Errors.None()
+}
+
+/**
+ * Name.........: readTag
+ * Parameters...: source (string) - the template
+ *                start (int) - where the tag starts
+ *                opening (string) - the opening of the tag
+ *                syntax (DataTypes.Syntax) - the delimiters
+ * Return.......: DataTypes.Token - the tag
+ *                int - the index of the closing of the tag, -1 if it is not closed
+ *                string - the closing of the tag
+ * Description..: Reads a tag, print or comment, and its trim markers
+ */
+func readTag(source string, start int, opening string, syntax DataTypes.Syntax) (DataTypes.Token, int, string) {
+	token := DataTypes.Token{Type: DataTypes.TagToken}
+	closing := syntax.TagClosing
+
+	if opening == syntax.PrintOpening {
+		token.Type, closing = DataTypes.PrintToken, syntax.PrintClosing
+	} else if opening == syntax.CommentOpening {
+		token.Type, closing = DataTypes.CommentToken, syntax.CommentClosing
+	}
+
+	inside := start + len(opening)
+	if inside < len(source) && source[inside] == '-' {
+		token.TrimBefore = true
+		inside++
+	}
+
+	end := -1
+	if token.Type == DataTypes.CommentToken {
+		// Comments can have anything in them, even a single quote
+		if end = strings.Index(source[inside:], closing); end >= 0 {
+			end = end + inside
+		}
+	} else {
+		end = findClosing(source, inside, closing)
+	}
+	if end < 0 {
+		return token, -1, closing
+	}
+
+	value := source[inside:end]
+	if end > inside && source[end-1] == '-' {
+		token.TrimAfter = true
+		value = source[inside : end-1]
+	}
+	token.Value = Helpers.Trim(value)
+
+	return token, end, closing
+}
+
+/**
+ * Name.........: trimText
+ * Parameters...: text (string) - the text between two tags
+ *                left (bool) - remove the whitespace at the start
+ *                right (bool) - remove the whitespace at the end
+ * Return.......: string
+ * Description..: Removes the whitespace trim markers ask for
+ */
+func trimText(text string, left bool, right bool) string {
+	if left {
+		text = strings.TrimLeft(text, " \t\r\n")
+	}
+	if right {
+		text = strings.TrimRight(text, " \t\r\n")
+	}
+
+	return text
+}
+
+/**
+ * Name.........: onlyTags
+ * Parameters...: source (string) - the template
+ *                lineStart (int) - where the line starts
+ *                lineEnd (int) - where the line ends
+ *                syntax (DataTypes.Syntax) - the delimiters
+ * Return.......: bool
+ * Description..: True if a line only has commands, comments and whitespace on it
+ */
+func onlyTags(source string, lineStart int, lineEnd int, syntax DataTypes.Syntax) bool {
+	pos := lineStart
+
+	for {
+		start, opening := findOpening(source, pos, syntax)
+		if start < 0 || start >= lineEnd {
+			return Helpers.Trim(source[pos:lineEnd]) == ""
+		}
+
+		if Helpers.Trim(source[pos:start]) != "" || opening == syntax.PrintOpening {
+			return false
+		}
+
+		_, end, closing := readTag(source, start, opening, syntax)
+		if end < 0 || end+len(closing) > lineEnd {
+			return false
+		}
+		pos = end + len(closing)
+	}
+}
+
+/**
+ * Name.........: findLine
+ * Parameters...: source (string) - the template
+ *                pos (int) - where the text before the tag starts
+ *                start (int) - where the tag starts
+ *                after (int) - where the tag ends
+ * Return.......: int - where the line of the tag starts
+ *                int - where the line of the tag ends, at the line break
+ *                bool - true if the tag is the only thing on its line
+ * Description..: Finds the line a tag is on
+ */
+func findLine(source string, pos int, start int, after int) (int, int, bool) {
+	lineStart := strings.LastIndex(source[:start], "\n") + 1
+	lineEnd := strings.Index(source[after:], "\n")
+	if lineEnd < 0 {
+		lineEnd = len(source)
+	} else {
+		lineEnd = after + lineEnd
+	}
+
+	alone := lineStart >= pos && Helpers.Trim(source[lineStart:start]) == "" && Helpers.Trim(source[after:lineEnd]) == ""
+
+	return lineStart, lineEnd, alone
+}
+
+/**
+ * Name.........: findRawEnd
+ * Parameters...: source (string) - the template
+ *                pos (int) - where the raw text starts
+ *                syntax (DataTypes.Syntax) - the delimiters
+ * Return.......: DataTypes.Token - the {% end raw %} tag, with its trim markers
+ *                int - where the {% end raw %} starts, -1 if there is none
+ *                int - where it ends
+ * Description..: Finds the end of a raw block
+ */
+func findRawEnd(source string, pos int, syntax DataTypes.Syntax) (DataTypes.Token, int, int) {
+	endRaw := regexp.MustCompile(regexp.QuoteMeta(syntax.TagOpening) + "(-?)\\s*end\\s+raw\\s*(-?)" + regexp.QuoteMeta(syntax.TagClosing))
+
+	match := endRaw.FindStringSubmatchIndex(source[pos:])
+	if match == nil {
+		return DataTypes.Token{}, -1, -1
+	}
+
+	token := DataTypes.Token{Type: DataTypes.TagToken, Value: "end raw", TrimBefore: match[3] > match[2], TrimAfter: match[5] > match[4]}
+
+	return token, pos + match[0], pos + match[1]
+}
+
+/**
+ * Name.........: IsSuper
+ * Parameters...: print (string) - the inside of a {{ }} print
+ * Return.......: bool
+ * Description..: True if the print is {{ super }}, the block of the template that is extended
+ */
+func IsSuper(print string) bool {
+	return print == "super" || print == "super()"
+}
+
+/**
+ * Name.........: appendText
+ * Parameters...: tokens ([]DataTypes.Token) - tokens so far
+ *                text (string) - the text to add
+ *                line (int) - the line the text starts on
+ * Return.......: []DataTypes.Token
+ * Description..: Adds a text token if there is any text
+ */
+func appendText(tokens []DataTypes.Token, text string, line int) []DataTypes.Token {
+	if text == "" {
+		return tokens
+	}
+
+	return append(tokens, DataTypes.Token{Type: DataTypes.TextToken, Value: text, Line: line})
+}
+
+/**
+ * Name.........: findOpening
+ * Parameters...: source (string) - the template
+ *                pos (int) - where to start looking
+ *                syntax (DataTypes.Syntax) - the delimiters
+ * Return.......: int - the index of the next opening tag, -1 if there is none
+ *                string - the opening tag found
+ * Description..: Finds the next opening tag of a command, print or comment. When two start at the same
+ *                place the longest one wins, so { and {{ can both be used
+ */
+func findOpening(source string, pos int, syntax DataTypes.Syntax) (int, string) {
+	start, opening := -1, ""
+
+	for _, tag := range []string{syntax.TagOpening, syntax.PrintOpening, syntax.CommentOpening} {
+		index := strings.Index(source[pos:], tag)
+		if index >= 0 && (start < 0 || index < start || (index == start && len(tag) > len(opening))) {
+			start, opening = index, tag
+		}
+	}
+
+	if start < 0 {
+		return -1, ""
+	}
+
+	return pos + start, opening
+}
+
+/**
+ * Name.........: findClosing
+ * Parameters...: source (string) - the template
+ *                pos (int) - where the inside of the tag starts
+ *                closing (string) - the closing tag
+ * Return.......: int - the index of the closing tag, -1 if there is none
+ * Description..: Finds the closing tag, skipping over anything in quotes
+ */
+func findClosing(source string, pos int, closing string) int {
+	quote := byte(0)
+
+	for i := pos; i < len(source); i++ {
+		c := source[i]
+
+		if quote != 0 {
+			if c == quote && source[i-1] != '\\' {
+				quote = 0
+			}
+		} else if c == '"' || c == '\'' {
+			quote = c
+		} else if strings.HasPrefix(source[i:], closing) {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -13,6 +13,33 @@ type DaphneComparisonOperators struct {
 
 var Comparison = DaphneComparisonOperators{Equal:"==",NotEqual:"!=",Larger:">",LargerOrEqual:">=",Smaller:"<",SmallerOrEqual:"<=",All:"(==|!=|>|>=|<|<=)"}
 
+type DaphneLogicalOperators struct {
+    And string
+    Or string
+    Not string
+}
+
+var Logical = DaphneLogicalOperators{And:"&&",Or:"||",Not:"!"}
+
+type DaphneTextOperators struct {
+    In string
+    Contains string
+    StartsWith string
+    EndsWith string
+}
+
+var Text = DaphneTextOperators{In:"in",Contains:"contains",StartsWith:"startswith",EndsWith:"endswith"}
+
+type DaphneArithmeticOperators struct {
+    Add string
+    Subtract string
+    Multiply string
+    Divide string
+    Modulo string
+}
+
+var Arithmetic = DaphneArithmeticOperators{Add:"+",Subtract:"-",Multiply:"*",Divide:"/",Modulo:"%"}
+
 
 
 /**
@@ -37,5 +64,58 @@ func IsComparisonOperator(inp string) (bool) {
 }
 
 func IsLogicalOperator(inp string) (bool) {
-    return inp == "||" || inp == "&&"
+    return inp == Logical.Or || inp == Logical.And
+}
+
+
+/**
+ * Name.........: IsTextOperator
+ * Parameters...: str (string) - string to check
+ * Return.......: bool - true or false
+ * Description..: Determines if a string is one of the word operators that work on text and lists
+ */
+func IsTextOperator(inp string) (bool) {
+    return inp == Text.In || inp == Text.Contains || inp == Text.StartsWith || inp == Text.EndsWith
+}
+
+
+/**
+ * Name.........: IsArithmeticOperator
+ * Parameters...: str (string) - string to check
+ * Return.......: bool - true or false
+ * Description..: Determines if a string is one of + - * / %
+ */
+func IsArithmeticOperator(inp string) (bool) {
+    return inp == Arithmetic.Add || inp == Arithmetic.Subtract || inp == Arithmetic.Multiply || inp == Arithmetic.Divide || inp == Arithmetic.Modulo
+}
+
+/**
+ * Name.........: IsAdditiveOperator
+ * Parameters...: str (string) - string to check
+ * Return.......: bool - true or false
+ * Description..: Determines if a string is + or -, which bind looser than * / %
+ */
+func IsAdditiveOperator(inp string) (bool) {
+    return inp == Arithmetic.Add || inp == Arithmetic.Subtract
+}
+
+
+/**
+ * Name.........: LogicalKeyword
+ * Parameters...: str (string) - the word to check
+ * Return.......: string - the logical operator the word stands for
+ *                bool - false if the word is not a logical keyword
+ * Description..: Maps the words and, or and not to &&, || and !
+ */
+func LogicalKeyword(inp string) (string, bool) {
+    switch inp {
+    case "and":
+        return Logical.And, true
+    case "or":
+        return Logical.Or, true
+    case "not":
+        return Logical.Not, true
+    }
+
+    return "", false
 }
@@ -0,0 +1,192 @@
+package Semantics
+
+import (
+	"daphne/DataTypes"
+	"daphne/Helpers"
+	"encoding/json"
+	"fmt"
+	"html"
+	"strings"
+)
+
+// URL schemes that are safe to link to
+var safeSchemes = []string{"http", "https", "mailto", "tel", "ftp"}
+
+/**
+ * Name.........: EscapeValue
+ * Parameters...: value (DataTypes.Value) - the value being printed
+ *                context (DataTypes.HTMLContext) - where in the HTML it is printed
+ *                position (DataTypes.TagPosition) - whether it is printed inside of a tag, and if it is quoted
+ * Return.......: string - the value, safe to put in that place
+ * Description..: Escapes a printed value for the place it is printed in. Safe values are printed as they are,
+ *                but a URL that could run code still becomes #
+ */
+func EscapeValue(value DataTypes.Value, context DataTypes.HTMLContext, position DataTypes.TagPosition) string {
+	str := value.String()
+
+	if value.Safe {
+		// Escaped HTML is still a link, &#58; is a :
+		if context == DataTypes.URLContext && !IsSafeURL(html.UnescapeString(str)) {
+			return "#"
+		}
+		return str
+	}
+
+	switch context {
+	case DataTypes.ScriptContext:
+		if value.JSON {
+			str = EscapeJSON(str)
+		} else {
+			str = EscapeScriptValue(value)
+		}
+
+	case DataTypes.ScriptStringContext:
+		str = EscapeScriptString(str)
+
+	case DataTypes.StyleContext:
+		str = EscapeStyle(str)
+
+	case DataTypes.URLContext:
+		if !IsSafeURL(str) {
+			str = "#"
+		}
+	}
+
+	// Script and style elements are not HTML, attributes and text are
+	if position != DataTypes.OutsideTag || context == DataTypes.TextContext {
+		str = html.EscapeString(str)
+	}
+
+	if position == DataTypes.UnquotedValue {
+		str = escapeUnquoted(str)
+	}
+
+	return str
+}
+
+/**
+ * Name.........: EscapeScriptValue
+ * Parameters...: value (DataTypes.Value) - the value being printed
+ * Return.......: string - the value as JavaScript
+ * Description..: Prints a value in code as the JavaScript value it is, text is put in quotes. <, > and & are
+ *                escaped so the value can not end the script element
+ */
+func EscapeScriptValue(value DataTypes.Value) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "null"
+	}
+
+	return string(encoded)
+}
+
+/**
+ * Name.........: EscapeJSON
+ * Parameters...: str (string) - text that is already JSON
+ * Return.......: string - the JSON, safe to put in a script element
+ * Description..: Escapes what could end the script element or a line of JavaScript, without changing the
+ *                value the JSON is read as
+ */
+func EscapeJSON(str string) string {
+	replacer := strings.NewReplacer("<", "\\u003c", ">", "\\u003e", "&", "\\u0026", "\u2028", "\\u2028", "\u2029", "\\u2029")
+	return replacer.Replace(str)
+}
+
+/**
+ * Name.........: EscapeScriptString
+ * Parameters...: str (string) - the text being printed
+ * Return.......: string - the text, safe to put inside of a JavaScript string
+ * Description..: Escapes everything that could end the string or the script element, or run code in a template
+ *                string
+ */
+func EscapeScriptString(str string) string {
+	result := strings.Builder{}
+
+	for _, c := range str {
+		switch c {
+		case '\\':
+			result.WriteString("\\\\")
+		case '\n':
+			result.WriteString("\\n")
+		case '\r':
+			result.WriteString("\\r")
+		case '\t':
+			result.WriteString("\\t")
+		case '/':
+			result.WriteString("\\/")
+		case '"', '\'', '`', '<', '>', '&', '=', '$', '\u2028', '\u2029':
+			result.WriteString(fmt.Sprintf("\\u%04x", c))
+		default:
+			if c < 0x20 || c == 0x7f {
+				result.WriteString(fmt.Sprintf("\\u%04x", c))
+			} else {
+				result.WriteRune(c)
+			}
+		}
+	}
+
+	return result.String()
+}
+
+/**
+ * Name.........: EscapeStyle
+ * Parameters...: str (string) - the text being printed
+ * Return.......: string - the text, safe to put in CSS
+ * Description..: Escapes everything that could end a CSS value, declaration, string or the style element
+ */
+func EscapeStyle(str string) string {
+	result := strings.Builder{}
+
+	for _, c := range str {
+		if c < 0x20 || c == 0x7f || strings.ContainsRune("\"&'()+/:;<>\\{}`=@!*", c) {
+			result.WriteString(fmt.Sprintf("\\%06x", c))
+		} else {
+			result.WriteRune(c)
+		}
+	}
+
+	return result.String()
+}
+
+/**
+ * Name.........: escapeUnquoted
+ * Parameters...: str (string) - a value that has been HTML escaped already
+ * Return.......: string - the value, safe to put in a tag without quotes around it
+ * Description..: Escapes the whitespace, = and ` that would end the value or start another attribute
+ */
+func escapeUnquoted(str string) string {
+	result := strings.Builder{}
+
+	for _, c := range str {
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '=' || c == '`' {
+			result.WriteString(fmt.Sprintf("&#%d;", c))
+		} else {
+			result.WriteRune(c)
+		}
+	}
+
+	return result.String()
+}
+
+/**
+ * Name.........: IsSafeURL
+ * Parameters...: url (string) - the url
+ * Return.......: bool
+ * Description..: True if the URL is relative, or uses a scheme that can not run code (javascript: can)
+ */
+func IsSafeURL(url string) bool {
+	url = Helpers.ToLower(Helpers.Trim(url))
+
+	colon := strings.Index(url, ":")
+	if colon < 0 || strings.ContainsAny(url[:colon], "/?#") {
+		return true
+	}
+
+	for _, scheme := range safeSchemes {
+		if url[:colon] == scheme {
+			return true
+		}
+	}
+
+	return false
+}
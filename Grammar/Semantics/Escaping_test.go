@@ -0,0 +1,130 @@
+package Semantics_test
+
+import (
+	"daphne/DataTypes"
+	"testing"
+)
+
+/**
+ * A template, the value it prints as x, and what it should render to
+ */
+type escapeTest struct {
+	name     string
+	template string
+	value    DataTypes.Value
+	expected string
+}
+
+/**
+ * Name.........: runEscapeTests
+ * Parameters...: t (*testing.T) - the test
+ *                tests ([]escapeTest) - the templates to render
+ * Description..: Renders every template with x set to the value of the test, and compares the result
+ */
+func runEscapeTests(t *testing.T, tests []escapeTest) {
+	t.Helper()
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ProgramState := newTestState()
+			ProgramState.Set("x", test.value, false)
+
+			if result := render(t, ProgramState, test.template); result != test.expected {
+				t.Errorf("rendering %q with x = %q\n got: %q\nwant: %q", test.template, test.value.String(), result, test.expected)
+			}
+		})
+	}
+}
+
+func TestEscapeText(t *testing.T) {
+	runEscapeTests(t, []escapeTest{
+		{"markup", "<p>{{ x }}</p>", DataTypes.NewString("<b>\"Tom\" & 'Jerry'</b>"), "<p>&lt;b&gt;&#34;Tom&#34; &amp; &#39;Jerry&#39;&lt;/b&gt;</p>"},
+		{"script tag", "<p>{{ x }}</p>", DataTypes.NewString("<script>alert(1)</script>"), "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>"},
+		{"whitespace is kept", "<p>{{ x }}</p>", DataTypes.NewString("a b=c"), "<p>a b=c</p>"},
+		{"safe", "<p>{{ x }}</p>", DataTypes.NewSafeString("<b>bold</b>"), "<p><b>bold</b></p>"},
+	})
+}
+
+func TestEscapeAttribute(t *testing.T) {
+	runEscapeTests(t, []escapeTest{
+		{"double quotes", "<div class=\"{{ x }}\">", DataTypes.NewString("a\" onmouseover=\"alert(1)"), "<div class=\"a&#34; onmouseover=&#34;alert(1)\">"},
+		{"single quotes", "<div class='{{ x }}'>", DataTypes.NewString("a' onmouseover='alert(1)"), "<div class='a&#39; onmouseover=&#39;alert(1)'>"},
+		{"quoted keeps whitespace", "<div title=\"{{ x }}\">", DataTypes.NewString("a b=c"), "<div title=\"a b=c\">"},
+		{"unquoted", "<div class={{ x }}>", DataTypes.NewString("a onmouseover=alert(1)"), "<div class=a&#32;onmouseover&#61;alert(1)>"},
+		{"unquoted whitespace", "<div class={{ x }}>", DataTypes.NewString("a\tb\nc"), "<div class=a&#9;b&#10;c>"},
+		{"unquoted backtick", "<div class={{ x }}>", DataTypes.NewString("`a`"), "<div class=&#96;a&#96;>"},
+		{"unquoted markup", "<div class={{ x }}>", DataTypes.NewString("a><script>"), "<div class=a&gt;&lt;script&gt;>"},
+		{"unquoted after text", "<div class=a{{ x }}>", DataTypes.NewString(" b"), "<div class=a&#32;b>"},
+		{"in the tag", "<div {{ x }}>", DataTypes.NewString("onmouseover=alert(1)"), "<div onmouseover&#61;alert(1)>"},
+	})
+}
+
+func TestEscapeURL(t *testing.T) {
+	runEscapeTests(t, []escapeTest{
+		{"javascript", "<a href=\"{{ x }}\">", DataTypes.NewString("javascript:alert(1)"), "<a href=\"#\">"},
+		{"javascript in upper case", "<a href=\"{{ x }}\">", DataTypes.NewString(" JavaScript:alert(1)"), "<a href=\"#\">"},
+		{"data", "<img src=\"{{ x }}\">", DataTypes.NewString("data:text/html,<script>"), "<img src=\"#\">"},
+		{"http", "<a href=\"{{ x }}\">", DataTypes.NewString("https://example.com/?a=1&b=2"), "<a href=\"https://example.com/?a=1&amp;b=2\">"},
+		{"relative", "<a href=\"{{ x }}\">", DataTypes.NewString("/blog/post?x=a:b"), "<a href=\"/blog/post?x=a:b\">"},
+		{"after the start", "<a href=\"/search?q={{ x }}\">", DataTypes.NewString("javascript:\"x\""), "<a href=\"/search?q=javascript:&#34;x&#34;\">"},
+		{"unquoted", "<a href={{ x }}>", DataTypes.NewString("/a b onclick=alert(1)"), "<a href=/a&#32;b&#32;onclick&#61;alert(1)>"},
+		{"escape filter", "<a href=\"{{ x | escape }}\">", DataTypes.NewString("javascript:alert(1)"), "<a href=\"#\">"},
+		{"safe filter", "<a href=\"{{ x | safe }}\">", DataTypes.NewString("javascript:alert(1)"), "<a href=\"#\">"},
+		{"safe with an entity", "<a href=\"{{ x }}\">", DataTypes.NewSafeString("javascript&#58;alert(1)"), "<a href=\"#\">"},
+		{"escape filter on a safe URL", "<a href=\"{{ x | escape }}\">", DataTypes.NewString("/search?a=1&b=2"), "<a href=\"/search?a=1&amp;b=2\">"},
+	})
+}
+
+func TestEscapeScript(t *testing.T) {
+	runEscapeTests(t, []escapeTest{
+		{"double quoted string", "<script>var t = \"{{ x }}\"</script>", DataTypes.NewString("a\"; alert(1); //"), "<script>var t = \"a\\u0022; alert(1); \\/\\/\"</script>"},
+		{"single quoted string", "<script>var t = '{{ x }}'</script>", DataTypes.NewString("a'; alert(1); //"), "<script>var t = 'a\\u0027; alert(1); \\/\\/'</script>"},
+		{"template string", "<script>var t = `{{ x }}`</script>", DataTypes.NewString("${alert(1)}`"), "<script>var t = `\\u0024{alert(1)}\\u0060`</script>"},
+		{"end of the element", "<script>var t = \"{{ x }}\"</script>", DataTypes.NewString("</script><script>alert(1)</script>"), "<script>var t = \"\\u003c\\/script\\u003e\\u003cscript\\u003ealert(1)\\u003c\\/script\\u003e\"</script>"},
+		{"backslash and lines", "<script>var t = \"{{ x }}\"</script>", DataTypes.NewString("a\\\"\nb\u2028c\u2029d"), "<script>var t = \"a\\\\\\u0022\\nb\\u2028c\\u2029d\"</script>"},
+		{"escaped quote before", "<script>var t = \"\\\" {{ x }}\"</script>", DataTypes.NewString("\""), "<script>var t = \"\\\" \\u0022\"</script>"},
+		{"text as a value", "<script>var t = {{ x }};</script>", DataTypes.NewString("a\"; alert(1); //"), "<script>var t = \"a\\\"; alert(1); //\";</script>"},
+		{"code as a value", "<script>var t = {{ x }};</script>", DataTypes.NewString("1; alert(1)"), "<script>var t = \"1; alert(1)\";</script>"},
+		{"end of the element as a value", "<script>var t = {{ x }};</script>", DataTypes.NewString("</script>"), "<script>var t = \"\\u003c/script\\u003e\";</script>"},
+		{"number", "<script>var n = {{ x }};</script>", DataTypes.ParseValue("3"), "<script>var n = 3;</script>"},
+		{"list", "<script>var n = {{ x }};</script>", DataTypes.ParseValue("[go, 3]"), "<script>var n = [\"go\",3];</script>"},
+		{"after a string", "<script>var a = \"it's\"; var t = {{ x }};</script>", DataTypes.NewString("a"), "<script>var a = \"it's\"; var t = \"a\";</script>"},
+		{"after a comment", "<script>// don't\nvar t = {{ x }};</script>", DataTypes.NewString("a"), "<script>// don't\nvar t = \"a\";</script>"},
+		{"after a block comment", "<script>/* \" */ var t = {{ x }};</script>", DataTypes.NewString("a"), "<script>/* \" */ var t = \"a\";</script>"},
+		{"safe", "<script>{{ x }}</script>", DataTypes.NewSafeString("alert(1)"), "<script>alert(1)</script>"},
+	})
+}
+
+func TestEscapeJSONFilter(t *testing.T) {
+	runEscapeTests(t, []escapeTest{
+		{"list", "<script>var d = {{ x | json }};</script>", DataTypes.ParseValue("[a, b]"), "<script>var d = [\"a\",\"b\"];</script>"},
+		{"map", "<script>var d = {{ x | json }};</script>", DataTypes.NewMap(map[string]DataTypes.Value{"n": DataTypes.ParseValue("1")}), "<script>var d = {\"n\":1};</script>"},
+		{"ld+json", "<script type=\"application/ld+json\">{{ x | json }}</script>", DataTypes.NewMap(map[string]DataTypes.Value{"name": DataTypes.NewString("Tom & Jerry")}), "<script type=\"application/ld+json\">{\"name\":\"Tom \\u0026 Jerry\"}</script>"},
+		{"end of the element", "<script>var d = {{ x | json }};</script>", DataTypes.NewString("</script><script>alert(1)//"), "<script>var d = \"\\u003c/script\\u003e\\u003cscript\\u003ealert(1)//\";</script>"},
+		{"line separators", "<script>var d = {{ x | json }};</script>", DataTypes.NewString("a\u2028b\u2029c"), "<script>var d = \"a\\u2028b\\u2029c\";</script>"},
+		{"in a string", "<script>var d = '{{ x | json }}';</script>", DataTypes.ParseValue("[a]"), "<script>var d = '[\\u0022a\\u0022]';</script>"},
+		{"in text", "<p>{{ x | json }}</p>", DataTypes.ParseValue("[a]"), "<p>[&#34;a&#34;]</p>"},
+		{"changed by another filter", "<script>var d = {{ x | json | upper }};</script>", DataTypes.ParseValue("[a]"), "<script>var d = \"[\\\"A\\\"]\";</script>"},
+	})
+}
+
+func TestEscapeScriptAttribute(t *testing.T) {
+	runEscapeTests(t, []escapeTest{
+		{"string", "<button onclick=\"go('{{ x }}')\">", DataTypes.NewString("'); alert(1); //"), "<button onclick=\"go('\\u0027); alert(1); \\/\\/')\">"},
+		{"value", "<button onclick=\"go({{ x }})\">", DataTypes.NewString("a\"); alert(1"), "<button onclick=\"go(&#34;a\\&#34;); alert(1&#34;)\">"},
+		{"unquoted value", "<button onclick=go({{ x }})>", DataTypes.NewString("a b"), "<button onclick=go(&#34;a&#32;b&#34;)>"},
+		{"upper case", "<body ONLOAD=\"go('{{ x }}')\">", DataTypes.NewString("'"), "<body ONLOAD=\"go('\\u0027')\">"},
+		{"not an event", "<div data-onclick=\"{{ x }}\">", DataTypes.NewString("'"), "<div data-onclick=\"&#39;\">"},
+	})
+}
+
+func TestEscapeStyle(t *testing.T) {
+	runEscapeTests(t, []escapeTest{
+		{"element", "<style>p { color: {{ x }}; }</style>", DataTypes.NewString("red; } body { display: none"), "<style>p { color: red\\00003b \\00007d body \\00007b display\\00003a none; }</style>"},
+		{"end of the element", "<style>p { font-family: \"{{ x }}\"; }</style>", DataTypes.NewString("</style><script>"), "<style>p { font-family: \"\\00003c\\00002fstyle\\00003e\\00003cscript\\00003e\"; }</style>"},
+		{"attribute", "<div style=\"color: {{ x }}\">", DataTypes.NewString("red; background: url(x)"), "<div style=\"color: red\\00003b background\\00003a url\\000028x\\000029\">"},
+		{"attribute quote", "<div style=\"color: {{ x }}\">", DataTypes.NewString("red\" onclick=\"alert(1)"), "<div style=\"color: red\\000022 onclick\\00003d\\000022alert\\0000281\\000029\">"},
+		{"unquoted attribute", "<div style=color:{{ x }}>", DataTypes.NewString("red onclick"), "<div style=color:red&#32;onclick>"},
+		{"plain value", "<div style=\"color: {{ x }}\">", DataTypes.NewString("#ff0000"), "<div style=\"color: #ff0000\">"},
+	})
+}
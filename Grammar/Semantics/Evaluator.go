@@ -0,0 +1,496 @@
+package Semantics
+
+import (
+	"daphne/DataTypes"
+	"daphne/Errors"
+	"daphne/FileSystem"
+	"daphne/Grammar"
+	"daphne/Grammar/Operators"
+	"daphne/Helpers"
+	"daphne/State"
+	"sort"
+	"strings"
+)
+
+/**
+ * Name.........: EvaluateTemplate
+ * Parameters...: nodes ([]DataTypes.Node) - the parsed template
+ *                ProgramState (*State.CompilerState) - The program state
+ * Return.......: string - the rendered template
+ *                Errors.Error - any errors
+ * Description..: Walks a parsed template once and renders it
+ */
+func EvaluateTemplate(nodes []DataTypes.Node, ProgramState *State.CompilerState) (string, Errors.Error) {
+	output := strings.Builder{}
+
+	err := evaluateNodes(nodes, &output, ProgramState)
+
+	return output.String(), err
+}
+
+/**
+ * Name.........: evaluateNodes
+ * Parameters...: nodes ([]DataTypes.Node) - the nodes to evaluate
+ *                output (*strings.Builder) - where to write the result
+ *                ProgramState (*State.CompilerState) - The program state
+ * Return.......: Errors.Error - any errors
+ * Description..: Evaluates a list of nodes in order, until a break or continue
+ */
+func evaluateNodes(nodes []DataTypes.Node, output *strings.Builder, ProgramState *State.CompilerState) Errors.Error {
+	for _, node := range nodes {
+		err := evaluateNode(node, output, ProgramState)
+		if err.HasError() {
+			return err
+		}
+
+		// A break or continue skips the rest of the body of the loop
+		if ProgramState.Jump != State.NoJump {
+			break
+		}
+	}
+
+	return Errors.None()
+}
+
+/**
+ * Name.........: evaluateNode
+ * Parameters...: node (DataTypes.Node) - the node to evaluate
+ *                output (*strings.Builder) - where to write the result
+ *                ProgramState (*State.CompilerState) - The program state
+ * Return.......: Errors.Error - any errors
+ * Description..: Evaluates a single node
+ */
+func evaluateNode(node DataTypes.Node, output *strings.Builder, ProgramState *State.CompilerState) Errors.Error {
+	switch cmd := node.(type) {
+	case DataTypes.TextNode:
+		output.WriteString(cmd.Text)
+
+	case DataTypes.PrintNode:
+		value, err := EvaluateValue(cmd.Expression, ProgramState)
+		if err.HasError() {
+			return Errors.NewFatal(err.Msg, " on line ", Helpers.ToStr(cmd.Line))
+		}
+
+		eval := value.String()
+		if ProgramState.EscapeHTML() {
+			eval = EscapeValue(value, cmd.Context, cmd.Position)
+		}
+
+		// Values that span multiple lines bring their own indentation
+		if !strings.Contains(eval, "\n") {
+			output.WriteString(cmd.Indent)
+		}
+		output.WriteString(eval)
+
+	case DataTypes.IfNode:
+		// The first branch that is true wins
+		for _, branch := range cmd.Branches {
+			isTrue, err := EvaluateCondition(branch.Condition, ProgramState)
+			if err.HasError() {
+				return Errors.NewFatal(err.Msg, " on line ", Helpers.ToStr(branch.Line))
+			}
+
+			if isTrue {
+				return evaluateNodes(branch.Body, output, ProgramState)
+			}
+		}
+		return evaluateNodes(cmd.Else, output, ProgramState)
+
+	case DataTypes.SwitchNode:
+		return evaluateSwitch(cmd, output, ProgramState)
+
+	case DataTypes.ForeachNode:
+		return evaluateForeach(cmd, output, ProgramState)
+
+	case DataTypes.BreakNode:
+		ProgramState.Jump = State.BreakJump
+
+	case DataTypes.ContinueNode:
+		ProgramState.Jump = State.ContinueJump
+
+	case DataTypes.SetNode:
+		err := EvaluateSetCommand(cmd.Variable, cmd.Value, cmd.Global, ProgramState)
+		if err.HasError() {
+			return Errors.NewFatal(err.Msg, " in the set on line ", Helpers.ToStr(cmd.Line))
+		}
+
+	case DataTypes.CaptureNode:
+		captured := strings.Builder{}
+		if err := evaluateNodes(cmd.Body, &captured, ProgramState); err.HasError() {
+			return err
+		}
+
+		// The values in it have been escaped already, the last line break belongs to the {% end capture %}
+		ProgramState.Set(cmd.Variable, DataTypes.NewSafeString(Helpers.TrimSuffix(captured.String(), "\n")), false)
+
+	case DataTypes.IncludeNode:
+		return evaluateInclude(cmd, output, ProgramState)
+
+	case DataTypes.MacroNode:
+		ProgramState.Macros[cmd.Name] = cmd
+
+	case DataTypes.ImportNode:
+		err := evaluateImport(cmd, ProgramState)
+		if err.HasError() {
+			return Errors.NewFatal(err.Msg, " for the import on line ", Helpers.ToStr(cmd.Line))
+		}
+
+	case DataTypes.ExtendsNode:
+		return Errors.NewFatal(ProgramState.Syntax().Tag("extends"), " can only be used in templates, found on line ", Helpers.ToStr(cmd.Line))
+
+	case DataTypes.BlockNode:
+		return evaluateBlock(cmd.Name, 0, cmd.Body, output, ProgramState)
+
+	case DataTypes.SuperNode:
+		if len(ProgramState.BlockStack) == 0 {
+			return Errors.NewFatal(ProgramState.Syntax().Print("super"), " can only be used inside of a block, found on line ", Helpers.ToStr(cmd.Line))
+		}
+
+		// The same block, one template further up
+		current := ProgramState.BlockStack[len(ProgramState.BlockStack)-1]
+		if current.Level+1 < len(ProgramState.Blocks[current.Name]) {
+			return evaluateBlock(current.Name, current.Level+1, nil, output, ProgramState)
+		}
+	}
+
+	return Errors.None()
+}
+
+/**
+ * Name.........: evaluateSwitch
+ * Parameters...: cmd (DataTypes.SwitchNode) - the switch
+ *                output (*strings.Builder) - where to write the result
+ *                ProgramState (*State.CompilerState) - The program state
+ * Return.......: Errors.Error - any errors
+ * Description..: Evaluates the first case with a value equal to the value of the switch, compared just like
+ *                == in an if statement, or the default if none of them are
+ */
+func evaluateSwitch(cmd DataTypes.SwitchNode, output *strings.Builder, ProgramState *State.CompilerState) Errors.Error {
+	value, err := EvaluateValue(cmd.Value, ProgramState)
+	if err.HasError() {
+		return Errors.NewFatal(err.Msg, " in the switch on line ", Helpers.ToStr(cmd.Line))
+	}
+
+	for _, branch := range cmd.Cases {
+		for _, expression := range branch.Values {
+			caseValue, err := EvaluateValue(expression, ProgramState)
+			if err.HasError() {
+				return Errors.NewFatal(err.Msg, " in the case on line ", Helpers.ToStr(branch.Line))
+			}
+
+			if EvaluateOperator(value, Operators.Comparison.Equal, caseValue) {
+				return evaluateNodes(branch.Body, output, ProgramState)
+			}
+		}
+	}
+
+	return evaluateNodes(cmd.Default, output, ProgramState)
+}
+
+/**
+ * Name.........: evaluateForeach
+ * Parameters...: cmd (DataTypes.ForeachNode) - the foreach loop
+ *                output (*strings.Builder) - where to write the result
+ *                ProgramState (*State.CompilerState) - The program state
+ * Return.......: Errors.Error - any errors
+ * Description..: Evaluates the body of a foreach loop once for every item, a break stops the loop and a
+ *                continue goes on with the next item
+ */
+func evaluateForeach(cmd DataTypes.ForeachNode, output *strings.Builder, ProgramState *State.CompilerState) Errors.Error {
+	// The loop this one is inside of, a macro or included file does not see the loops of its caller
+	parent := ProgramState.Meta.Nearest(DataTypes.LoopScope, DataTypes.MacroScope, DataTypes.IncludeScope)
+
+	items, err := LoopItems(cmd, ProgramState)
+	if err.HasError() {
+		return Errors.NewFatal(err.Msg, " in the foreach on line ", Helpers.ToStr(cmd.Line))
+	}
+
+	items, err = FilterLoopItems(cmd, items, ProgramState)
+	if err.HasError() {
+		return Errors.NewFatal(err.Msg, " in the foreach on line ", Helpers.ToStr(cmd.Line))
+	}
+
+	for i, newMeta := range items {
+		AddLoopMeta(newMeta, i, len(items), parent)
+
+		// Push the new meta to the meta stack, and evaluate the body
+		ProgramState.Meta.Push(newMeta, DataTypes.LoopScope)
+		err := evaluateNodes(cmd.Body, output, ProgramState)
+		ProgramState.Meta.Pop()
+
+		// The break or continue was meant for this loop
+		jump := ProgramState.Jump
+		ProgramState.Jump = State.NoJump
+
+		if err.HasError() {
+			return err
+		} else if jump == State.BreakJump {
+			break
+		}
+	}
+
+	return Errors.None()
+}
+
+/**
+ * Name.........: evaluateBlock
+ * Parameters...: name (string) - the name of the block
+ *                level (int) - which definition of the block to use, 0 is the template that overrides all the others
+ *                body ([]DataTypes.Node) - the body to use if no template defines the block
+ *                output (*strings.Builder) - where to write the result
+ *                ProgramState (*State.CompilerState) - The program state
+ * Return.......: Errors.Error - any errors
+ * Description..: Evaluates a definition of a block, and remembers which one for {{ super }}
+ */
+func evaluateBlock(name string, level int, body []DataTypes.Node, output *strings.Builder, ProgramState *State.CompilerState) Errors.Error {
+	if definitions := ProgramState.Blocks[name]; level < len(definitions) {
+		body = definitions[level]
+	}
+
+	ProgramState.BlockStack = append(ProgramState.BlockStack, State.BlockLevel{Name: name, Level: level})
+	err := evaluateNodes(body, output, ProgramState)
+	ProgramState.BlockStack = ProgramState.BlockStack[:len(ProgramState.BlockStack)-1]
+
+	return err
+}
+
+/**
+ * Name.........: LoopItems
+ * Parameters...: cmd (DataTypes.ForeachNode) - the foreach loop
+ *                ProgramState (*State.CompilerState) - The program state
+ * Return.......: []map[string]DataTypes.Value - the variables of every item, before the clauses are applied
+ *                Errors.Error - any errors
+ * Description..: Finds what a foreach loop goes through: the pages of a collection, a range of numbers or
+ *                any value. Lists give every item, maps every value sorted by key, and text every part
+ *                between commas. Nothing is looped through when the value does not exist
+ */
+func LoopItems(cmd DataTypes.ForeachNode, ProgramState *State.CompilerState) ([]map[string]DataTypes.Value, Errors.Error) {
+	items := []map[string]DataTypes.Value{}
+
+	// The pages of a collection keep all of their meta under the alias
+	if pages, isCollection := ProgramState.Special[cmd.Collection]; isCollection && cmd.Key == "" {
+		for _, pg := range pages {
+			items = append(items, AliasMeta(pg.Meta, cmd.Alias))
+		}
+		return items, Errors.None()
+	}
+
+	var value DataTypes.Value
+	var err Errors.Error
+	if isRange, start, end := Grammar.IsRange(cmd.Collection); isRange {
+		value, err = EvaluateRange(start, end, ProgramState)
+	} else {
+		value, err = EvaluateValue(cmd.Collection, ProgramState)
+	}
+	if err.HasError() {
+		return nil, err
+	}
+
+	if cmd.Key != "" && value.Kind != DataTypes.MapValue && !value.IsNull() {
+		return nil, Errors.NewFatal("Only a map has keys, ", cmd.Collection, " is a ", kindNames[value.Kind])
+	}
+
+	switch value.Kind {
+	case DataTypes.ListValue:
+		for _, item := range value.List {
+			items = append(items, map[string]DataTypes.Value{cmd.Alias: item})
+		}
+
+	case DataTypes.MapValue:
+		for _, key := range value.Keys() {
+			item := map[string]DataTypes.Value{cmd.Alias: value.Map[key]}
+			if cmd.Key != "" {
+				item[cmd.Key] = DataTypes.NewString(key)
+			}
+			items = append(items, item)
+		}
+
+	case DataTypes.StringValue:
+		for _, part := range DataTypes.SplitList(value.Str) {
+			if part != "" {
+				items = append(items, map[string]DataTypes.Value{cmd.Alias: DataTypes.ParseValue(part)})
+			}
+		}
+
+	case DataTypes.NullValue:
+		// Like a page without any tags
+
+	default:
+		return nil, Errors.NewFatal("Can not loop through ", cmd.Collection, ", it is a ", kindNames[value.Kind])
+	}
+
+	return items, Errors.None()
+}
+
+/**
+ * Name.........: AliasMeta
+ * Parameters...: meta (map[string]DataTypes.Value) - the meta of a page
+ *                alias (string) - the alias of the foreach loop
+ * Return.......: map[string]DataTypes.Value - the meta with the keys renamed
+ * Description..: Renames the meta keys of a page to the alias specified in a foreach loop
+ */
+func AliasMeta(meta map[string]DataTypes.Value, alias string) map[string]DataTypes.Value {
+	newMeta := make(map[string]DataTypes.Value)
+
+	for key, val := range meta {
+		keys := Helpers.Split(key, ".")
+		newKey := keys[0]
+
+		if len(keys) > 1 {
+			newKey = alias + "." + Helpers.Join(keys[1:], ".")
+		}
+		newMeta[newKey] = val
+	}
+
+	return newMeta
+}
+
+/**
+ * Name.........: FilterLoopItems
+ * Parameters...: cmd (DataTypes.ForeachNode) - the foreach loop
+ *                items ([]map[string]DataTypes.Value) - the meta of every item in the collection
+ *                ProgramState (*State.CompilerState) - The program state
+ * Return.......: []map[string]DataTypes.Value - the items to loop through
+ *                Errors.Error - any errors
+ * Description..: Applies the where, order by, offset and limit clauses of a foreach loop, in that order
+ */
+func FilterLoopItems(cmd DataTypes.ForeachNode, items []map[string]DataTypes.Value, ProgramState *State.CompilerState) ([]map[string]DataTypes.Value, Errors.Error) {
+	if cmd.Where != "" {
+		filtered := []map[string]DataTypes.Value{}
+
+		for _, item := range items {
+			ProgramState.Meta.Push(item, DataTypes.LoopScope)
+			keep, err := EvaluateCondition(cmd.Where, ProgramState)
+			ProgramState.Meta.Pop()
+
+			if err.HasError() {
+				return nil, err
+			} else if keep {
+				filtered = append(filtered, item)
+			}
+		}
+		items = filtered
+	}
+
+	if cmd.OrderBy != "" {
+		keys := make([]DataTypes.Value, len(items))
+		order := make([]int, len(items))
+
+		for i, item := range items {
+			ProgramState.Meta.Push(item, DataTypes.LoopScope)
+			keys[i] = EvaluateVariable(cmd.OrderBy, ProgramState)
+			ProgramState.Meta.Pop()
+
+			order[i] = i
+		}
+
+		sort.SliceStable(order, func(a int, b int) bool {
+			if cmd.Descending {
+				return CompareValues(keys[order[a]], keys[order[b]]) > 0
+			}
+			return CompareValues(keys[order[a]], keys[order[b]]) < 0
+		})
+
+		sorted := []map[string]DataTypes.Value{}
+		for _, i := range order {
+			sorted = append(sorted, items[i])
+		}
+		items = sorted
+	}
+
+	if cmd.Offset != "" {
+		offset, isInt := Helpers.ToInt(EvaluateVariable(cmd.Offset, ProgramState).String())
+		if !isInt || offset < 0 {
+			return nil, Errors.NewFatal("The offset has to be a positive number, got ", cmd.Offset)
+		}
+
+		if offset > len(items) {
+			offset = len(items)
+		}
+		items = items[offset:]
+	}
+
+	if cmd.Limit != "" {
+		limit, isInt := Helpers.ToInt(EvaluateVariable(cmd.Limit, ProgramState).String())
+		if !isInt || limit < 0 {
+			return nil, Errors.NewFatal("The limit has to be a positive number, got ", cmd.Limit)
+		}
+
+		if limit < len(items) {
+			items = items[:limit]
+		}
+	}
+
+	return items, Errors.None()
+}
+
+/**
+ * Name.........: evaluateInclude
+ * Parameters...: cmd (DataTypes.IncludeNode) - the include command
+ *                output (*strings.Builder) - where to write the result
+ *                ProgramState (*State.CompilerState) - The program state
+ * Return.......: Errors.Error - any errors
+ * Description..: Parses and evaluates a file from the include directory in place
+ */
+func evaluateInclude(cmd DataTypes.IncludeNode, output *strings.Builder, ProgramState *State.CompilerState) Errors.Error {
+	file := ProgramState.Include(cmd.File)
+
+	contents, err := FileSystem.ReadFile(file)
+	if err.HasError() {
+		return err
+	}
+
+	nodes, err := Grammar.ParseTemplate(Helpers.Join(contents, "\n")+"\n", ProgramState.Syntax())
+	if err.HasError() {
+		return Errors.NewFatal(file, ": ", err.Msg)
+	}
+
+	// The arguments are evaluated where the include is, and only exist inside of the included file
+	scope := make(map[string]DataTypes.Value)
+
+	for name, expression := range cmd.Arguments {
+		value, err := EvaluateValue(expression, ProgramState)
+		if err.HasError() {
+			return Errors.NewFatal(err.Msg, " in the argument ", name, " of the include on line ", Helpers.ToStr(cmd.Line))
+		}
+		scope["include."+name] = value
+	}
+
+	ProgramState.Meta.Push(scope, DataTypes.IncludeScope)
+	result, err := EvaluateTemplate(nodes, ProgramState)
+	ProgramState.Meta.Pop()
+	if err.HasError() {
+		return Errors.NewFatal(file, ": ", err.Msg)
+	}
+
+	// Only keep the last line break if the include took the place of its whole line
+	if !cmd.Standalone {
+		result = Helpers.TrimSuffix(result, "\n")
+	}
+	output.WriteString(result)
+
+	return Errors.None()
+}
+
+/**
+ * Name.........: AddLoopMeta
+ * Parameters...: meta (map[string]DataTypes.Value) - the meta of the current iteration
+ *                index (int) - the zero based index of the iteration
+ *                length (int) - the number of iterations
+ *                parent (map[string]DataTypes.Value) - the meta of the loop this one is inside of
+ * Description..: Adds the loop.* variables to the meta of a foreach iteration, and
+ *                the loop.* variables of an outer loop as loop.parent.*
+ */
+func AddLoopMeta(meta map[string]DataTypes.Value, index int, length int, parent map[string]DataTypes.Value) {
+	meta["loop.index"] = DataTypes.NewNumber(float64(index + 1))
+	meta["loop.index0"] = DataTypes.NewNumber(float64(index))
+	meta["loop.length"] = DataTypes.NewNumber(float64(length))
+	meta["loop.first"] = DataTypes.NewBool(index == 0)
+	meta["loop.last"] = DataTypes.NewBool(index == length-1)
+
+	for key, val := range parent {
+		if Helpers.Substring(key, 0, 4) == "loop." {
+			meta["loop.parent."+key[5:]] = val
+		}
+	}
+}
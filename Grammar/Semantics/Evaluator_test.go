@@ -0,0 +1,679 @@
+package Semantics_test
+
+import (
+	"daphne/DataTypes"
+	"daphne/Grammar"
+	"daphne/Grammar/Semantics"
+	"daphne/Parser"
+	"daphne/State"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+/**
+ * A template and what it should render to
+ */
+type renderTest struct {
+	name     string
+	template string
+	expected string
+}
+
+/**
+ * Name.........: newTestState
+ * Return.......: *State.CompilerState - a state like the one index.html is expanded with
+ * Description..: Builds a site with a config, two posts and a page, without reading any files
+ */
+func newTestState() *State.CompilerState {
+	ProgramState := State.NewCompilerState()
+
+	ProgramState.Config = map[string]DataTypes.Value{
+		"site.url":   DataTypes.NewString("http://example.com/"),
+		"site.title": DataTypes.NewString("My Website"),
+	}
+	Parser.ApplyDefaultConfigOptions(ProgramState.Config)
+
+	ProgramState.Special = map[string][]DataTypes.Page{
+		"site.posts": {
+			newTestPage("Second", "/blog/second", "2017-02-01"),
+			newTestPage("First", "/blog/first", "2017-01-01"),
+		},
+	}
+
+	ProgramState.CurrentPage = newTestPage("Home", "/", "2017-03-01")
+	ProgramState.CurrentPage.OutFile = "_build\\index.html"
+	ProgramState.CurrentPage.Meta["page.author"] = DataTypes.NewString("John Doe")
+	ProgramState.CurrentPage.Meta["page.template"] = DataTypes.NewString("default")
+	ProgramState.CurrentPage.Meta["page.tags"] = DataTypes.ParseValue("[go, web]")
+
+	meta := make(map[string]DataTypes.Value)
+	for key, value := range ProgramState.CurrentPage.Meta {
+		meta[key] = value
+	}
+	ProgramState.Meta.Push(meta, DataTypes.PageScope)
+
+	return ProgramState
+}
+
+/**
+ * Name.........: newTestPage
+ * Parameters...: title (string) - the title of the page
+ *                url (string) - the url of the page
+ *                date (string) - the date of the page
+ * Return.......: DataTypes.Page
+ * Description..: Builds a page with the meta the page parser would give it
+ */
+func newTestPage(title string, url string, date string) DataTypes.Page {
+	return DataTypes.Page{
+		File: title + ".html",
+		Meta: map[string]DataTypes.Value{
+			"page.title":      DataTypes.NewString(title),
+			"page.url":        DataTypes.NewString(url),
+			"page.date_short": DataTypes.ParseValue(date),
+		},
+	}
+}
+
+/**
+ * Name.........: render
+ * Parameters...: t (*testing.T) - the test
+ *                ProgramState (*State.CompilerState) - The program state
+ *                template (string) - the template to render
+ * Return.......: string - the rendered template
+ * Description..: Parses and evaluates a template, failing the test on any errors
+ */
+func render(t *testing.T, ProgramState *State.CompilerState, template string) string {
+	t.Helper()
+
+	nodes, err := Grammar.ParseTemplate(template, ProgramState.Syntax())
+	if err.HasError() {
+		t.Fatalf("parsing %q: %s", template, err.Msg)
+	}
+
+	result, err := Semantics.EvaluateTemplate(nodes, ProgramState)
+	if err.HasError() {
+		t.Fatalf("evaluating %q: %s", template, err.Msg)
+	}
+
+	return result
+}
+
+/**
+ * Name.........: renderError
+ * Parameters...: t (*testing.T) - the test
+ *                template (string) - the template to render
+ * Return.......: string - the message of the error
+ * Description..: Parses and evaluates a template that should fail, failing the test if it does not
+ */
+func renderError(t *testing.T, template string) string {
+	t.Helper()
+
+	ProgramState := newTestState()
+
+	nodes, err := Grammar.ParseTemplate(template, ProgramState.Syntax())
+	if !err.HasError() {
+		var result string
+		result, err = Semantics.EvaluateTemplate(nodes, ProgramState)
+		if !err.HasError() {
+			t.Fatalf("expected %q to fail, got %q", template, result)
+		}
+	}
+
+	return err.Msg
+}
+
+/**
+ * Name.........: runRenderTests
+ * Parameters...: t (*testing.T) - the test
+ *                tests ([]renderTest) - the templates to render
+ * Description..: Renders every template with a new state and compares the result
+ */
+func runRenderTests(t *testing.T, tests []renderTest) {
+	t.Helper()
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if result := render(t, newTestState(), test.template); result != test.expected {
+				t.Errorf("rendering %q\n got: %q\nwant: %q", test.template, result, test.expected)
+			}
+		})
+	}
+}
+
+/**
+ * Name.........: writeTestFile
+ * Parameters...: t (*testing.T) - the test
+ *                name (string) - the path of the file, with \ like every other path in Daphne
+ *                contents (string) - what to write
+ * Description..: Writes a file in the directory the test runs in
+ */
+func writeTestFile(t *testing.T, name string, contents string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+/**
+ * Name.........: inTempDir
+ * Parameters...: t (*testing.T) - the test
+ * Description..: Runs the rest of the test in an empty directory, for tests that read files
+ */
+func inTempDir(t *testing.T) {
+	t.Helper()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func TestRenderText(t *testing.T) {
+	runRenderTests(t, []renderTest{
+		{"empty", "", ""},
+		{"plain text", "<p>Hello</p>\n", "<p>Hello</p>\n"},
+		{"no trailing line break", "<p>Hello</p>", "<p>Hello</p>"},
+		{"braces that are not tags", "a { b } c %}\n", "a { b } c %}\n"},
+	})
+}
+
+func TestRenderPrints(t *testing.T) {
+	runRenderTests(t, []renderTest{
+		{"page variables", "<h1>{{ page.title }} by {{ page.author }}</h1>\n", "<h1>Home by John Doe</h1>\n"},
+		{"config variables", "<title>{{ site.title }}</title>\n", "<title>My Website</title>\n"},
+		{"string literal", "{{ \"quoted\" }}\n", "quoted\n"},
+		{"missing variable", "[{{ page.missing }}]\n", "[]\n"},
+		{"ternary true", "<h1>{{ (page.template == \"default\") ? \"Default\" : \"Other\" }}</h1>\n", "<h1>Default</h1>\n"},
+		{"ternary false", "<h1>{{ (page.template == \"post\") ? \"Post\" : \"Not a post\" }}</h1>\n", "<h1>Not a post</h1>\n"},
+		{"concatenate literal", "<img src=\"{{ site.url + \"assets/header.jpg\" }}\">\n", "<img src=\"http://example.com/assets/header.jpg\">\n"},
+		{"concatenate variables", "{{ page.title + page.author }}\n", "HomeJohn Doe\n"},
+		{"indented print", "\t{{ page.title }}\n", "\tHome\n"},
+	})
+}
+
+func TestRenderIf(t *testing.T) {
+	runRenderTests(t, []renderTest{
+		{"true", "{% if page.title == \"Home\" %}\n\t<h1>Home</h1>\n{% end if %}\n", "\t<h1>Home</h1>\n"},
+		{"false", "{% if page.title == \"About\" %}\n\t<h1>About</h1>\n{% end if %}\n", ""},
+		{"else", "{% if page.title == \"About\" %}\n\tabout\n{% else %}\n\tnot about\n{% end if %}\n", "\tnot about\n"},
+		{"or", "{% if page.title == \"About\" || page.author == \"John Doe\" %}\nyes\n{% end if %}\n", "yes\n"},
+		{"and", "{% if page.title == \"Home\" && page.author == \"Someone\" %}\nyes\n{% else %}\nno\n{% end if %}\n", "no\n"},
+		{"variable", "{% if page.author %}\nby {{ page.author }}\n{% end if %}\n", "by John Doe\n"},
+		{"text around", "<div>\n{% if page.title %}\n<h1>{{ page.title }}</h1>\n{% end if %}\n</div>\n", "<div>\n<h1>Home</h1>\n</div>\n"},
+	})
+}
+
+func TestRenderForeach(t *testing.T) {
+	runRenderTests(t, []renderTest{
+		{"posts", "<ul>\n{% foreach site.posts as post %}\n\t<li>{{ post.title }}</li>\n{% end foreach %}\n</ul>\n", "<ul>\n\t<li>Second</li>\n\t<li>First</li>\n</ul>\n"},
+		{"empty collection", "{% foreach site.pages as nav %}\n<a>{{ nav.title }}</a>\n{% end foreach %}\n", ""},
+		{"page variables inside", "{% foreach site.posts as post %}\n{{ page.title }}/{{ post.title }}\n{% end foreach %}\n", "Home/Second\nHome/First\n"},
+		{"if inside", "{% foreach site.posts as post %}\n{% if post.title == \"First\" %}\n<b>{{ post.title }}</b>\n{% else %}\n{{ post.title }}\n{% end if %}\n{% end foreach %}\n", "Second\n<b>First</b>\n"},
+		{"nested", "{% foreach site.posts as a %}\n{% foreach site.posts as b %}\n{{ a.title }}-{{ b.title }}\n{% end foreach %}\n{% end foreach %}\n", "Second-Second\nSecond-First\nFirst-Second\nFirst-First\n"},
+	})
+}
+
+func TestRenderForeachClauses(t *testing.T) {
+	runRenderTests(t, []renderTest{
+		{"where", "{% foreach site.posts as post where post.title == \"First\" %}\n{{ post.title }}\n{% end foreach %}\n", "First\n"},
+		{"where nothing", "{% foreach site.posts as post where post.title == \"Third\" %}\n{{ post.title }}\n{% end foreach %}\n", ""},
+		{"order by", "{% foreach site.posts as post order by post.date_short %}\n{{ post.title }}\n{% end foreach %}\n", "First\nSecond\n"},
+		{"order by desc", "{% foreach site.posts as post order by post.title desc %}\n{{ post.title }}\n{% end foreach %}\n", "Second\nFirst\n"},
+		{"order numbers", "{% foreach [5, 3, 8, 1] as n order by n %}{{ n }} {% end foreach %}\n", "1 3 5 8 \n"},
+		{"limit", "{% foreach site.posts as post limit 1 %}\n{{ post.title }}\n{% end foreach %}\n", "Second\n"},
+		{"offset", "{% foreach site.posts as post offset 1 %}\n{{ post.title }}\n{% end foreach %}\n", "First\n"},
+		{"offset past the end", "{% foreach site.posts as post offset 5 %}\n{{ post.title }}\n{% end foreach %}\n", ""},
+		{"limit zero", "{% foreach site.posts as post limit 0 %}\n{{ post.title }}\n{% end foreach %}\n", ""},
+		{"all clauses", "{% foreach [5, 3, 8, 1, 7] as n where n > 2 order by n desc limit 2 offset 1 %}{{ n }} {% end foreach %}\n", "7 5 \n"},
+		{"limit from a variable", "{% set max = 1 %}\n{% foreach site.posts as post limit max %}\n{{ post.title }}\n{% end foreach %}\n", "Second\n"},
+		{"loop index counts what is left", "{% foreach site.posts as post offset 1 %}\n{{ loop.index }}/{{ loop.length }}\n{% end foreach %}\n", "1/1\n"},
+	})
+}
+
+func TestRenderForeachClauseErrors(t *testing.T) {
+	for _, template := range []string{
+		"{% foreach site.posts as post limit -1 %}\n{% end foreach %}\n",
+		"{% foreach site.posts as post limit \"a\" %}\n{% end foreach %}\n",
+		"{% foreach site.posts as post offset -1 %}\n{% end foreach %}\n",
+		"{% foreach site.posts as post offset \"a\" %}\n{% end foreach %}\n",
+	} {
+		renderError(t, template)
+	}
+}
+
+func TestRenderSet(t *testing.T) {
+	runRenderTests(t, []renderTest{
+		{"literal", "{% set greeting = \"Hello\" %}\n{{ greeting }}\n", "Hello\n"},
+		{"variable", "{% set heading = page.title %}\n<h1>{{ heading }}</h1>\n", "<h1>Home</h1>\n"},
+		{"changed later", "{% set x = \"a\" %}\n{{ x }}\n{% set x = \"b\" %}\n{{ x }}\n", "a\nb\n"},
+	})
+}
+
+func TestRenderInclude(t *testing.T) {
+	inTempDir(t)
+	writeTestFile(t, "_includes\\header.html", "<header>{{ page.title }}</header>")
+	writeTestFile(t, "_includes\\footer.html", "{% if page.author %}\n<footer>{{ page.author }}</footer>\n{% end if %}\n")
+
+	runRenderTests(t, []renderTest{
+		{"own line", "<body>\n{% include header.html %}\n</body>\n", "<body>\n<header>Home</header>\n</body>\n"},
+		{"commands inside", "{% include footer.html %}\n", "<footer>John Doe</footer>\n"},
+		{"inside of a loop", "{% foreach site.posts as post %}\n{% include header.html %}\n{% end foreach %}\n", "<header>Home</header>\n<header>Home</header>\n"},
+	})
+}
+
+func TestRenderErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+	}{
+		{"unclosed if", "{% if page.title %}\nyes\n"},
+		{"unclosed foreach", "{% foreach site.posts as post %}\n"},
+		{"end without start", "{% end if %}\n"},
+		{"wrong end", "{% if page.title %}\n{% end foreach %}\n"},
+		{"unknown command", "{% frobnicate %}\n"},
+		{"unclosed print", "{{ page.title\n"},
+		{"missing include", "{% include missing.html %}\n"},
+	}
+
+	inTempDir(t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			renderError(t, test.template)
+		})
+	}
+}
+
+func TestRenderElseIf(t *testing.T) {
+	chain := "{% if page.title == \"About\" %}\nabout\n{% elif page.title == \"Home\" %}\nhome\n{% else if page.title %}\nother\n{% else %}\nnone\n{% end if %}\n"
+
+	runRenderTests(t, []renderTest{
+		{"second branch", chain, "home\n"},
+		{"first true branch wins", "{% if page.title %}\na\n{% elif page.title == \"Home\" %}\nb\n{% end if %}\n", "a\n"},
+		{"else if", "{% if page.title == \"About\" %}\na\n{% else if page.author == \"John Doe\" %}\nb\n{% end if %}\n", "b\n"},
+		{"else after branches", "{% if page.x %}\nx\n{% elif page.y %}\ny\n{% else %}\nelse\n{% end if %}\n", "else\n"},
+		{"no branch", "{% if page.x %}\nx\n{% elif page.y %}\ny\n{% end if %}\n", ""},
+		{"inline", "<li class=\"{% if page.x %}x{% elif page.title %}active{% else %}none{% end if %}\">\n", "<li class=\"active\">\n"},
+		{"nested", "{% if page.title %}\n{% if page.x %}\nx\n{% elif page.author %}\n{{ page.author }}\n{% end if %}\n{% else %}\nno\n{% end if %}\n", "John Doe\n"},
+		{"nested in else", "{% if page.x %}\nx\n{% else %}\n{% if page.y %}\ny\n{% else if page.title %}\n{{ page.title }}\n{% end if %}\n{% end if %}\n", "Home\n"},
+		{"inside of a loop", "{% foreach site.posts as post %}\n{% if post.title == \"Second\" %}\n2\n{% elif post.title == \"First\" %}\n1\n{% else %}\n?\n{% end if %}\n{% end foreach %}\n", "2\n1\n"},
+	})
+}
+
+func TestRenderLoopParent(t *testing.T) {
+	inTempDir(t)
+	writeTestFile(t, "_includes\\inner.html", "{% foreach site.posts as inner %}\n[{{ loop.parent.index }}].{{ loop.index }}\n{% end foreach %}\n")
+	writeTestFile(t, "_includes\\outer.html", "{% foreach site.posts as a %}\n{% foreach site.posts as b %}\n{{ loop.parent.index }}.{{ loop.index }}\n{% end foreach %}\n{% end foreach %}\n")
+
+	runRenderTests(t, []renderTest{
+		{"nested loops", "{% foreach site.posts as a %}\n{% foreach site.posts as b %}\n{{ loop.parent.index }}.{{ loop.index }}\n{% end foreach %}\n{% end foreach %}\n", "1.1\n1.2\n2.1\n2.2\n"},
+		{"not through an include", "{% foreach site.posts as post %}\n{% include inner.html %}\n{% end foreach %}\n", "[].1\n[].2\n[].1\n[].2\n"},
+		{"inside of an include", "{% foreach site.posts as post %}\n{% include outer.html %}\n{% end foreach %}\n", "1.1\n1.2\n2.1\n2.2\n1.1\n1.2\n2.1\n2.2\n"},
+		{"outermost loop", "{% foreach site.posts as post %}\n[{{ loop.parent.index }}]\n{% end foreach %}\n", "[]\n[]\n"},
+	})
+}
+
+func TestRenderErrorsUseSyntax(t *testing.T) {
+	ProgramState := newTestState()
+	ProgramState.Config["compiler.tags.opening"] = DataTypes.NewString("<%")
+	ProgramState.Config["compiler.tags.closing"] = DataTypes.NewString("%>")
+	ProgramState.Config["compiler.tags.print.opening"] = DataTypes.NewString("<%=")
+	ProgramState.Config["compiler.tags.print.closing"] = DataTypes.NewString("%>")
+
+	tests := []struct {
+		template string
+		expected string
+	}{
+		{"<%= super %>\n", "<%= super %> can only be used inside of a block, found on line 1"},
+		{"A\n<% extends default %>\n", "<% extends %> can only be used in templates, found on line 2"},
+	}
+
+	for _, test := range tests {
+		nodes, err := Grammar.ParseTemplate(test.template, ProgramState.Syntax())
+		if !err.HasError() {
+			_, err = Semantics.EvaluateTemplate(nodes, ProgramState)
+		}
+
+		if err.Msg != test.expected {
+			t.Errorf("rendering %q: expected the error %q, got %q", test.template, test.expected, err.Msg)
+		}
+	}
+}
+
+func TestRenderInheritance(t *testing.T) {
+	inTempDir(t)
+	writeTestFile(t, "_templates\\base.html", "<title>{% block title %}{{ site.title }}{% end block %}</title>\n{% block body %}\nempty\n{% end block %}\n")
+	writeTestFile(t, "_templates\\page.html", "{% extends base %}\n{% block title %}{{ page.title }}{% end block %}\n{% block body %}\n<main>{{ page.author }}</main>\n{% end block %}\n")
+	writeTestFile(t, "_templates\\super.html", "{% extends base %}\n{% block title %}{{ page.title }} - {{ super }}{% end block %}\n")
+	writeTestFile(t, "_templates\\post.html", "{% extends page.html %}\n{% block body %}\n<article>\n{{ super }}\n</article>\n{% end block %}\n")
+
+	tests := []renderTest{
+		{"no extends", "base", "<title>My Website</title>\nempty\n"},
+		{"override", "page", "<title>Home</title>\n<main>John Doe</main>\n"},
+		{"super", "super", "<title>Home - My Website</title>\nempty\n"},
+		{"two levels", "post", "<title>Home</title>\n<article>\n<main>John Doe</main>\n</article>\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ProgramState := newTestState()
+
+			nodes, blocks, err := Parser.LoadTemplate(test.template, ProgramState)
+			if err.HasError() {
+				t.Fatalf("loading %q: %s", test.template, err.Msg)
+			}
+
+			ProgramState.Blocks = blocks
+			result, err := Semantics.EvaluateTemplate(nodes, ProgramState)
+			if err.HasError() {
+				t.Fatalf("evaluating %q: %s", test.template, err.Msg)
+			}
+			if result != test.expected {
+				t.Errorf("rendering %q\n got: %q\nwant: %q", test.template, result, test.expected)
+			}
+		})
+	}
+}
+
+func TestRenderInheritanceErrors(t *testing.T) {
+	inTempDir(t)
+	writeTestFile(t, "_templates\\self.html", "{% extends self %}\n")
+	writeTestFile(t, "_templates\\a.html", "{% extends b %}\n")
+	writeTestFile(t, "_templates\\b.html", "{% extends a.html %}\n")
+	writeTestFile(t, "_templates\\twice.html", "{% extends a %}\n{% extends b %}\n")
+	writeTestFile(t, "_templates\\missing.html", "{% extends nothing %}\n")
+
+	for _, name := range []string{"self", "a", "twice", "missing"} {
+		t.Run(name, func(t *testing.T) {
+			if _, _, err := Parser.LoadTemplate(name, newTestState()); !err.HasError() {
+				t.Errorf("expected loading %q to fail", name)
+			}
+		})
+	}
+
+	// The cycle is named in the error, in the order the templates were read
+	_, _, err := Parser.LoadTemplate("a", newTestState())
+	if expected := "Templates extend each other: a.html extends b.html extends a.html"; err.Msg != expected {
+		t.Errorf("got %q, want %q", err.Msg, expected)
+	}
+
+	for _, template := range []string{
+		"{% extends base %}\n",
+		"{{ super }}\n",
+	} {
+		renderError(t, template)
+	}
+}
+
+func TestRenderIncludeArguments(t *testing.T) {
+	inTempDir(t)
+	writeTestFile(t, "_includes\\card.html", "<a href=\"{{ include.url }}\">{{ include.title }}</a>{% if include.note %} <small>{{ include.note }}</small>{% end if %}\n")
+	writeTestFile(t, "_includes\\nested.html", "{% include card.html title=include.title url=\"/\" %}\n")
+
+	runRenderTests(t, []renderTest{
+		{"literals", "{% include card.html title=\"Home\" url=\"/\" %}\n", "<a href=\"/\">Home</a>\n"},
+		{"variables", "{% include card.html title=page.title url=page.url note=page.author %}\n", "<a href=\"/\">Home</a> <small>John Doe</small>\n"},
+		{"expression", "{% include card.html title=page.title url=\"/\" note=\"by \" + page.author %}\n", "<a href=\"/\">Home</a> <small>by John Doe</small>\n"},
+		{"filter", "{% include card.html title=page.title | upper url=\"/\" %}\n", "<a href=\"/\">HOME</a>\n"},
+		{"loop variables", "{% foreach site.posts as post %}\n{% include card.html title=post.title url=post.url %}\n{% end foreach %}\n", "<a href=\"/blog/second\">Second</a>\n<a href=\"/blog/first\">First</a>\n"},
+		{"missing argument", "{% include card.html url=\"/\" %}\n", "<a href=\"/\"></a>\n"},
+		{"gone after the include", "{% include card.html title=\"Home\" url=\"/\" %}\n[{{ include.title }}]\n", "<a href=\"/\">Home</a>\n[]\n"},
+		{"include in an include", "{% include nested.html title=\"Inner\" %}\n", "<a href=\"/\">Inner</a>\n"},
+	})
+}
+
+func TestRenderIncludeArgumentErrors(t *testing.T) {
+	inTempDir(t)
+	writeTestFile(t, "_includes\\card.html", "{{ include.title }}\n")
+
+	for _, template := range []string{
+		"{% include card.html title=(1 + ) %}\n",
+		"{% include card.html title=page.title | missing %}\n",
+		"{% include card.html =\"a\" %}\n",
+	} {
+		renderError(t, template)
+	}
+}
+
+func TestRenderRawAndComments(t *testing.T) {
+	runRenderTests(t, []renderTest{
+		{"comment", "a{# hidden #}b\n", "ab\n"},
+		{"comment on its own line", "a\n{# hidden #}\nb\n", "a\nb\n"},
+		{"comment over lines", "a\n{# one\n{{ page.title }}\n{% if %} #}\nb\n", "a\nb\n"},
+		{"commands in a comment", "{# {% frobnicate %} {{ page.missing | missing }} #}\n", ""},
+		{"raw", "{% raw %}\n<h1>{{ page.title }}</h1>\n{% if page.title %}\n{% end raw %}\n", "<h1>{{ page.title }}</h1>\n{% if page.title %}\n"},
+		{"raw inline", "a {% raw %}{{ x }}{% end raw %} b\n", "a {{ x }} b\n"},
+		{"comment in raw", "{% raw %}{# kept #}{% end raw %}\n", "{# kept #}\n"},
+		{"raw in a comment", "{# {% raw %} #}{{ page.title }}\n", "Home\n"},
+		{"raw is not escaped", "{% raw %}<b>&amp;</b>{% end raw %}\n", "<b>&amp;</b>\n"},
+		{"raw inside of a loop", "{% foreach site.posts as post %}{% raw %}{{ post.title }}{% end raw %}\n{% end foreach %}", "{{ post.title }}\n{{ post.title }}\n"},
+	})
+}
+
+func TestRenderRawAndCommentErrors(t *testing.T) {
+	for _, template := range []string{
+		"{# not closed\n",
+		"{% raw %}\nnot closed\n",
+	} {
+		renderError(t, template)
+	}
+}
+
+func TestRenderTrimMarkers(t *testing.T) {
+	runRenderTests(t, []renderTest{
+		{"trim before", "a  \n\t{%- if page.title %}b{% end if %}\n", "ab\n"},
+		{"trim after", "{% if page.title -%}\n\n  b{% end if %}\n", "b\n"},
+		{"trim print", "<p>\n\t{{- page.title -}}\n</p>\n", "<p>Home</p>\n"},
+		{"trim comment", "a\n{#- hidden -#}\nb\n", "ab\n"},
+		{"list", "<ul>\n\t{%- foreach site.posts as post -%}\n\t<li>{{ post.title }}</li>\n\t{%- end foreach %}\n</ul>\n", "<ul><li>Second</li><li>First</li></ul>\n"},
+		{"only whitespace is removed", "a {%- if page.title -%} b {% end if %}\n", "ab \n"},
+		{"minus in an expression", "{{ 5 - 2 }}|{{-3}}\n", "3|3\n"},
+		{"tags on a line are kept", "{% if page.title %}{% if page.author %}\nyes\n{% end if %}{% end if %}\n", "\nyes\n\n"},
+	})
+}
+
+func TestRenderTrimTagLines(t *testing.T) {
+	ProgramState := newTestState()
+	ProgramState.Config["compiler.trim_tag_lines"] = DataTypes.NewString("true")
+
+	tests := []renderTest{
+		{"two tags", "{% if page.title %}{% if page.author %}\nyes\n{% end if %}{% end if %}\n", "yes\n"},
+		{"tag and comment", "\t{% if page.title %}{# why #}\nyes\n{% end if %}\n", "yes\n"},
+		{"print is kept", "{% if page.title %}{{ page.title }}\n{% end if %}\n", "Home\n"},
+		{"text is kept", "{% if page.title %}a{% end if %}\n", "a\n"},
+		{"one tag", "a\n{% set x = 1 %}\nb\n", "a\nb\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if result := render(t, ProgramState, test.template); result != test.expected {
+				t.Errorf("rendering %q\n got: %q\nwant: %q", test.template, result, test.expected)
+			}
+		})
+	}
+}
+
+func TestRenderScopes(t *testing.T) {
+	inTempDir(t)
+	writeTestFile(t, "_includes\\set.html", "{% set x = \"include\" %}\n{{ x }}\n")
+	writeTestFile(t, "_includes\\global.html", "{% set global x = \"include\" %}\n")
+	writeTestFile(t, "_includes\\read.html", "{{ x }}|{{ post.title }}\n")
+
+	runRenderTests(t, []renderTest{
+		{"loop variable is gone after the loop", "{% foreach site.posts as post %}{% end foreach %}[{{ post.title }}]\n", "[]\n"},
+		{"set in a loop is gone after the iteration", "{% foreach site.posts as post %}\n[{{ last.title }}]\n{% set last.title = post.title %}\n{% end foreach %}\n[{{ last.title }}]\n", "[]\n[]\n[]\n"},
+		{"set global in a loop", "{% foreach site.posts as post limit 1 %}\n{% set global newest = post.title %}\n{% end foreach %}\n{{ newest }}\n", "Second\n"},
+		{"loop sees the page", "{% set x = \"page\" %}\n{% foreach site.posts as post %}\n{{ x }}\n{% end foreach %}\n", "page\npage\n"},
+		{"set in an include", "{% set x = \"page\" %}\n{% include set.html %}\n{{ x }}\n", "include\npage\n"},
+		{"set global in an include", "{% set x = \"page\" %}\n{% include global.html %}\n{{ x }}\n", "include\n"},
+		{"include sees the loop", "{% set x = \"page\" %}\n{% foreach site.posts as post %}\n{% include read.html %}\n{% end foreach %}\n", "page|Second\npage|First\n"},
+		{"hides the config", "{% set site.title = \"Other\" %}\n{{ site.title }}\n", "Other\n"},
+		{"hides the config until the scope ends", "{% foreach site.posts as post limit 1 %}\n{% set site.title = post.title %}\n{{ site.title }}\n{% end foreach %}\n{{ site.title }}\n", "Second\nMy Website\n"},
+		{"hides the page meta", "{% foreach site.posts as post limit 1 %}\n{% set page.title = \"Other\" %}\n{{ page.title }}\n{% end foreach %}\n{{ page.title }}\n", "Other\nHome\n"},
+	})
+}
+
+func TestRenderSetKeepsConfig(t *testing.T) {
+	ProgramState := newTestState()
+	render(t, ProgramState, "{% set site.title = \"Other\" %}\n{% set global site.url = \"/\" %}\n")
+
+	if title := ProgramState.Config["site.title"].String(); title != "My Website" {
+		t.Errorf("site.title was changed to %q", title)
+	}
+	if url := ProgramState.Config["site.url"].String(); url != "http://example.com/" {
+		t.Errorf("site.url was changed to %q", url)
+	}
+}
+
+func TestRenderCapture(t *testing.T) {
+	runRenderTests(t, []renderTest{
+		{"without the last line break", "{% capture greeting %}\nHello\n{% end capture %}\n[{{ greeting }}]\n", "[Hello]\n"},
+		{"inline", "{% capture greeting %}Hello {{ page.author }}{% end capture %}{{ greeting }}!\n", "Hello John Doe!\n"},
+		{"nothing printed where it is", "a\n{% capture x %}\nb\n{% end capture %}\nc\n", "a\nc\n"},
+		{"commands inside", "{% capture titles %}{% foreach site.posts as post %}{{ post.title }},{% end foreach %}{% end capture %}{{ titles }}\n", "Second,First,\n"},
+		{"used twice", "{% capture x %}{{ page.title }}{% end capture %}{{ x }}|{{ x }}\n", "Home|Home\n"},
+		{"not escaped again", "{% capture x %}{{ \"<b>\" }}<i>{% end capture %}{{ x }}\n", "&lt;b&gt;<i>\n"},
+		{"in an attribute", "{% capture x %}{{ page.author }}{% end capture %}<meta content=\"{{ x }}\">\n", "<meta content=\"John Doe\">\n"},
+		{"filters", "{% capture x %}{{ page.title }}{% end capture %}{{ x | upper }}\n", "HOME\n"},
+		{"gone after the iteration", "{% foreach site.posts as post %}{% capture last.title %}{{ post.title }}{% end capture %}{% end foreach %}[{{ last.title }}]\n", "[]\n"},
+		{"nested", "{% capture a %}1{% capture b %}2{% end capture %}{{ b }}{% end capture %}{{ a }}\n", "12\n"},
+	})
+}
+
+func TestRenderCaptureErrors(t *testing.T) {
+	for _, template := range []string{
+		"{% capture %}\n{% end capture %}\n",
+		"{% capture x %}\nnot closed\n",
+		"{% capture x %}{{ page.title | missing }}{% end capture %}\n",
+	} {
+		renderError(t, template)
+	}
+}
+
+func TestRenderBreakContinue(t *testing.T) {
+	runRenderTests(t, []renderTest{
+		{"break", "{% foreach [1, 2, 3, 4] as n %}{% if n == 3 %}{% break %}{% end if %}{{ n }} {% end foreach %}\n", "1 2 \n"},
+		{"continue", "{% foreach [1, 2, 3, 4] as n %}{% if n == 2 %}{% continue %}{% end if %}{{ n }} {% end foreach %}\n", "1 3 4 \n"},
+		{"rest of the body is skipped", "{% foreach site.posts as post %}\n{{ post.title }}\n{% break %}\nafter\n{% end foreach %}\n", "Second\n"},
+		{"on their own lines", "{% foreach site.posts as post %}\n\t{% if post.title == \"Second\" %}\n\t\t{% continue %}\n\t{% end if %}\n\t<a>{{ post.title }}</a>\n{% end foreach %}\n", "\t<a>First</a>\n"},
+		{"inside of an else", "{% foreach [1, 2, 3] as n %}{% if n < 2 %}{{ n }}{% else %}{% break %}{% end if %}{% end foreach %}\n", "1\n"},
+		{"inside of a switch", "{% foreach [1, 2, 3] as n %}{% switch n %}{% case 2 %}{% continue %}{% end switch %}{{ n }}{% end foreach %}\n", "13\n"},
+		{"only the innermost loop", "{% foreach [1, 2] as a %}{% foreach [1, 2, 3] as b %}{% if b == 2 %}{% break %}{% end if %}{{ a }}{{ b }} {% end foreach %}{% end foreach %}\n", "11 21 \n"},
+		{"after an inner loop", "{% foreach [1, 2, 3] as a %}{% foreach [1] as b %}{% end foreach %}{{ a }}{% break %}{% end foreach %}\n", "1\n"},
+		{"text after the loop", "{% foreach site.posts as post %}{% break %}{% end foreach %}done\n", "done\n"},
+		{"inside of a capture", "{% foreach [1, 2, 3] as n %}{% capture x %}{{ n }}{% if n == 2 %}{% break %}{% end if %}{% end capture %}{{ x }}{% end foreach %}\n", "1\n"},
+	})
+}
+
+func TestRenderBreakContinueErrors(t *testing.T) {
+	inTempDir(t)
+	writeTestFile(t, "_includes\\break.html", "{% break %}\n")
+
+	for _, template := range []string{
+		"{% break %}\n",
+		"{% continue %}\n",
+		"{% if page.title %}{% break %}{% end if %}\n",
+		"{% foreach site.posts as post %}{% break now %}{% end foreach %}\n",
+		"{% foreach site.posts as post %}{% include break.html %}{% end foreach %}\n",
+		"{% macro stop() %}{% break %}{% end macro %}\n",
+	} {
+		renderError(t, template)
+	}
+}
+
+func TestRenderForeachValues(t *testing.T) {
+	runRenderTests(t, []renderTest{
+		{"list", "{% foreach page.tags as tag %}<span>{{ tag }}</span>{% end foreach %}\n", "<span>go</span><span>web</span>\n"},
+		{"list literal", "{% foreach [\"a\", 2, true] as x %}{{ x }} {% end foreach %}\n", "a 2 true \n"},
+		{"range", "{% foreach 1..5 as i %}{{ i }} {% end foreach %}\n", "1 2 3 4 5 \n"},
+		{"range counting down", "{% foreach 3..1 as i %}{{ i }} {% end foreach %}\n", "3 2 1 \n"},
+		{"range of one", "{% foreach 2..2 as i %}{{ i }} {% end foreach %}\n", "2 \n"},
+		{"range from variables", "{% set n = 3 %}{% foreach 1..n as i %}{{ i }} {% end foreach %}\n", "1 2 3 \n"},
+		{"range from a calculation", "{% foreach 0..page.tags.length - 1 as i %}{{ i }} {% end foreach %}\n", "0 1 \n"},
+		{"negative range", "{% foreach -1..1 as i %}{{ i }} {% end foreach %}\n", "-1 0 1 \n"},
+		{"text between commas", "{% set keywords = \"go, web,, html\" %}{% foreach keywords as k %}[{{ k }}]{% end foreach %}\n", "[go][web][html]\n"},
+		{"missing value", "{% foreach page.missing as x %}{{ x }}{% end foreach %}done\n", "done\n"},
+		{"loop meta", "{% foreach page.tags as tag %}{{ loop.index }}/{{ loop.length }}{% if loop.last %}!{% end if %} {% end foreach %}\n", "1/2 2/2! \n"},
+		{"where on values", "{% foreach 1..6 as i where i % 2 == 0 %}{{ i }} {% end foreach %}\n", "2 4 6 \n"},
+		{"key of a missing map", "{% foreach page.missing as key, value %}{{ key }}{% end foreach %}done\n", "done\n"},
+	})
+}
+
+func TestRenderForeachMaps(t *testing.T) {
+	ProgramState := newTestState()
+	ProgramState.Config["site.social.twitter"] = DataTypes.NewString("https://twitter.com/daphne")
+	ProgramState.Config["site.social.github"] = DataTypes.NewString("https://github.com/daphne")
+
+	var team DataTypes.Value
+	if err := json.Unmarshal([]byte(`[{"name": "Ann", "role": "Editor"}, {"name": "Bob", "links": {"web": "/bob"}}]`), &team); err != nil {
+		t.Fatal(err)
+	}
+	ProgramState.Config["site.data.team"] = team
+
+	nav, err := Parser.ParseConfig([]string{"main: {", "\thome: /", "\tabout: /about", "}"})
+	if err.HasError() {
+		t.Fatal(err.Msg)
+	}
+	for key, value := range nav {
+		ProgramState.Config["site.data.nav."+key] = value
+	}
+
+	tests := []renderTest{
+		{"values sorted by key", "{% foreach site.social as url %}{{ url }} {% end foreach %}\n", "https://github.com/daphne https://twitter.com/daphne \n"},
+		{"key and value", "{% foreach site.social as name, url %}<a href=\"{{ url }}\">{{ name }}</a>{% end foreach %}\n", "<a href=\"https://github.com/daphne\">github</a><a href=\"https://twitter.com/daphne\">twitter</a>\n"},
+		{"json list of objects", "{% foreach site.data.team as member %}{{ member.name }}{% if member.role %} ({{ member.role }}){% end if %} {% end foreach %}\n", "Ann (Editor) Bob \n"},
+		{"json inside of json", "{% foreach site.data.team as member where member.links %}{% foreach member.links as link, url %}{{ link }}={{ url }}{% end foreach %}{% end foreach %}\n", "web=/bob\n"},
+		{"config file", "{% foreach site.data.nav.main as title, url %}{{ title }}:{{ url }} {% end foreach %}\n", "about:/about home:/ \n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if result := render(t, ProgramState, test.template); result != test.expected {
+				t.Errorf("rendering %q\n got: %q\nwant: %q", test.template, result, test.expected)
+			}
+		})
+	}
+}
+
+func TestRenderDataFiles(t *testing.T) {
+	// The data directory is read with \ paths
+	if runtime.GOOS != "windows" {
+		t.Skip("data files are only found on Windows")
+	}
+
+	inTempDir(t)
+	writeTestFile(t, "_data\\team.json", `[{"name": "Ann"}, {"name": "Bob"}]`)
+	writeTestFile(t, "_data\\links\\social.daphne", "accounts: {\n\tgithub: https://github.com/daphne\n}\n")
+
+	ProgramState := newTestState()
+	if err := Parser.ParseDataFiles(ProgramState); err.HasError() {
+		t.Fatal(err.Msg)
+	}
+
+	template := "{% foreach site.data.team as member %}{{ member.name }} {% end foreach %}{% foreach site.data.links.social.accounts as name, url %}{{ name }}={{ url }}{% end foreach %}\n"
+	if result := render(t, ProgramState, template); result != "Ann Bob github=https://github.com/daphne\n" {
+		t.Errorf("got %q", result)
+	}
+}
+
+func TestRenderForeachValueErrors(t *testing.T) {
+	for _, template := range []string{
+		"{% foreach 1..\"a\" as i %}{% end foreach %}\n",
+		"{% foreach 1..2.5 as i %}{% end foreach %}\n",
+		"{% foreach page.tags as key, value %}{% end foreach %}\n",
+		"{% foreach true as x %}{% end foreach %}\n",
+		"{% foreach 5 as x %}{% end foreach %}\n",
+	} {
+		renderError(t, template)
+	}
+}
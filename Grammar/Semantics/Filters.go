@@ -0,0 +1,245 @@
+package Semantics
+
+import (
+	"daphne/DataTypes"
+	"daphne/Errors"
+	"daphne/Grammar"
+	"daphne/Helpers"
+	"daphne/State"
+	"encoding/json"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+/**
+ * A filter for print commands, gets the value and the evaluated arguments
+ */
+type Filter func(value DataTypes.Value, args []DataTypes.Value) (DataTypes.Value, Errors.Error)
+
+var filters = make(map[string]Filter)
+
+var htmlTagRegex, _ = regexp.Compile("<[^>]*>")
+
+/**
+ * Name.........: RegisterFilter
+ * Parameters...: name (string) - the name used in templates
+ *                filter (Filter) - the filter
+ * Description..: Adds a filter that can be used in print commands, replaces any filter with the same name
+ */
+func RegisterFilter(name string, filter Filter) {
+	filters[Helpers.ToLower(name)] = filter
+}
+
+/**
+ * Name.........: EvaluateFilter
+ * Parameters...: value (DataTypes.Value) - the value to filter
+ *                filter (string) - the filter name followed by its arguments
+ *                ProgramState (*State.CompilerState) - The program state
+ * Return.......: DataTypes.Value - the filtered value
+ *                Errors.Error - any errors
+ * Description..: Evaluates the arguments of a filter and runs it, every argument can be an expression like
+ *                the value of a print
+ */
+func EvaluateFilter(value DataTypes.Value, filter string, ProgramState *State.CompilerState) (DataTypes.Value, Errors.Error) {
+	tokens := Grammar.SplitArguments(filter)
+	if len(tokens) == 0 {
+		return value, Errors.NewFatal("Missing filter name after |")
+	}
+
+	name := Helpers.ToLower(tokens[0])
+
+	fn, exists := filters[name]
+	if !exists {
+		return value, Errors.NewFatal("Unknown filter ", tokens[0])
+	}
+
+	args := []DataTypes.Value{}
+	for _, arg := range tokens[1:] {
+		eval, err := EvaluateValue(arg, ProgramState)
+		if err.HasError() {
+			return value, Errors.NewFatal(err.Msg, " in the arguments of the filter ", name)
+		}
+		args = append(args, eval)
+	}
+
+	result, err := fn(value, args)
+	if err.HasError() {
+		return value, Errors.NewFatal("Filter ", name, ": ", err.Msg)
+	}
+
+	return result, Errors.None()
+}
+
+/**
+ * Name.........: ExpectArguments
+ * Parameters...: args ([]DataTypes.Value) - the arguments given
+ *                min (int) - the least arguments allowed
+ *                max (int) - the most arguments allowed
+ * Return.......: Errors.Error
+ * Description..: Makes sure a filter got the right number of arguments
+ */
+func ExpectArguments(args []DataTypes.Value, min int, max int) Errors.Error {
+	if len(args) < min || len(args) > max {
+		if min == max {
+			return Errors.NewFatal("expected ", Helpers.ToStr(min), " argument(s), got ", Helpers.ToStr(len(args)))
+		}
+		return Errors.NewFatal("expected ", Helpers.ToStr(min), " to ", Helpers.ToStr(max), " arguments, got ", Helpers.ToStr(len(args)))
+	}
+
+	return Errors.None()
+}
+
+/**
+ * Built-in filters
+ */
+func init() {
+	RegisterFilter("upper", stringFilter(0, 0, func(value string, args []string) (string, Errors.Error) {
+		return strings.ToUpper(value), Errors.None()
+	}))
+
+	RegisterFilter("lower", stringFilter(0, 0, func(value string, args []string) (string, Errors.Error) {
+		return Helpers.ToLower(value), Errors.None()
+	}))
+
+	// First letter upper case, the rest lower case
+	RegisterFilter("capitalize", stringFilter(0, 0, func(value string, args []string) (string, Errors.Error) {
+		first, size := utf8.DecodeRuneInString(value)
+		if size == 0 {
+			return value, Errors.None()
+		}
+		return strings.ToUpper(string(first)) + Helpers.ToLower(value[size:]), Errors.None()
+	}))
+
+	// truncate length [ending], the ending ("..." by default) is added if anything was cut off
+	RegisterFilter("truncate", stringFilter(1, 2, func(value string, args []string) (string, Errors.Error) {
+		length, isInt := Helpers.ToInt(args[0])
+		if !isInt || length < 0 {
+			return "", Errors.NewFatal("the length has to be a positive number, got ", args[0])
+		}
+
+		ending := "..."
+		if len(args) > 1 {
+			ending = args[1]
+		}
+
+		runes := []rune(value)
+		if len(runes) <= length {
+			return value, Errors.None()
+		}
+		return Helpers.Trim(string(runes[:length])) + ending, Errors.None()
+	}))
+
+	// replace search replacement
+	RegisterFilter("replace", stringFilter(2, 2, func(value string, args []string) (string, Errors.Error) {
+		return Helpers.Replace(value, args[0], args[1]), Errors.None()
+	}))
+
+	// default fallback, used when the value is empty
+	RegisterFilter("default", func(value DataTypes.Value, args []DataTypes.Value) (DataTypes.Value, Errors.Error) {
+		if err := ExpectArguments(args, 1, 1); err.HasError() {
+			return value, err
+		}
+		if Helpers.Trim(value.String()) == "" {
+			return args[0], Errors.None()
+		}
+		return value, Errors.None()
+	})
+
+	// Escaped values are not escaped again when they are printed
+	RegisterFilter("escape", func(value DataTypes.Value, args []DataTypes.Value) (DataTypes.Value, Errors.Error) {
+		if err := ExpectArguments(args, 0, 0); err.HasError() {
+			return value, err
+		}
+		return DataTypes.NewSafeString(html.EscapeString(value.String())), Errors.None()
+	})
+
+	// Marks the value as trusted HTML that is printed without escaping it
+	RegisterFilter("safe", safeFilter)
+	RegisterFilter("raw", safeFilter)
+
+	RegisterFilter("url_encode", stringFilter(0, 0, func(value string, args []string) (string, Errors.Error) {
+		return url.QueryEscape(value), Errors.None()
+	}))
+
+	RegisterFilter("slugify", stringFilter(0, 0, func(value string, args []string) (string, Errors.Error) {
+		return Helpers.URLSafe(value), Errors.None()
+	}))
+
+	// The value as JSON, lists and maps become arrays and objects. In a script it is printed as it is
+	RegisterFilter("json", func(value DataTypes.Value, args []DataTypes.Value) (DataTypes.Value, Errors.Error) {
+		if err := ExpectArguments(args, 0, 0); err.HasError() {
+			return value, err
+		}
+
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return value, Errors.NewFatal(err.Error())
+		}
+		return DataTypes.NewJSONString(string(encoded)), Errors.None()
+	})
+
+	// The text without tags, entities like &amp; become the character they stand for
+	RegisterFilter("strip_html", stringFilter(0, 0, func(value string, args []string) (string, Errors.Error) {
+		return html.UnescapeString(htmlTagRegex.ReplaceAllString(value, "")), Errors.None()
+	}))
+
+	// date layout, the layout uses Go's reference date (Mon Jan 2 15:04:05 2006)
+	RegisterFilter("date", func(value DataTypes.Value, args []DataTypes.Value) (DataTypes.Value, Errors.Error) {
+		if err := ExpectArguments(args, 1, 1); err.HasError() {
+			return value, err
+		}
+
+		date, isDate := value.Date, value.Kind == DataTypes.DateValue
+		if !isDate {
+			date, isDate = DataTypes.ParseDate(value.String())
+		}
+		if !isDate {
+			return value, Errors.NewFatal(value.String(), " is not a date")
+		}
+		return DataTypes.NewString(date.Format(args[0].String())), Errors.None()
+	})
+}
+
+/**
+ * Name.........: safeFilter
+ * Parameters...: value (DataTypes.Value) - the value
+ *                args ([]DataTypes.Value) - the arguments, there should be none
+ * Return.......: DataTypes.Value - the value, marked as safe
+ *                Errors.Error - any errors
+ * Description..: The safe and raw filters
+ */
+func safeFilter(value DataTypes.Value, args []DataTypes.Value) (DataTypes.Value, Errors.Error) {
+	if err := ExpectArguments(args, 0, 0); err.HasError() {
+		return value, err
+	}
+
+	value.Safe = true
+	return value, Errors.None()
+}
+
+/**
+ * Name.........: stringFilter
+ * Parameters...: min (int) - the least arguments allowed
+ *                max (int) - the most arguments allowed
+ *                fn (func) - the filter working on text
+ * Return.......: Filter
+ * Description..: Wraps a filter that only works on text, the value and arguments are passed as text
+ */
+func stringFilter(min int, max int, fn func(value string, args []string) (string, Errors.Error)) Filter {
+	return func(value DataTypes.Value, args []DataTypes.Value) (DataTypes.Value, Errors.Error) {
+		if err := ExpectArguments(args, min, max); err.HasError() {
+			return value, err
+		}
+
+		strArgs := []string{}
+		for _, arg := range args {
+			strArgs = append(strArgs, arg.String())
+		}
+
+		result, err := fn(value.String(), strArgs)
+		return DataTypes.NewString(result), err
+	}
+}
@@ -0,0 +1,48 @@
+package Semantics_test
+
+import (
+	"testing"
+)
+
+func TestRenderFilters(t *testing.T) {
+	runRenderTests(t, []renderTest{
+		{"upper", "{{ page.title | upper }}\n", "HOME\n"},
+		{"lower", "{{ page.author | lower }}\n", "john doe\n"},
+		{"capitalize", "{{ \"hELLO\" | capitalize }}\n", "Hello\n"},
+		{"truncate", "{{ page.author | truncate 4 }}\n", "John...\n"},
+		{"truncate ending", "{{ page.author | truncate 4 \"!\" }}\n", "John!\n"},
+		{"replace", "{{ page.author | replace \"John\" \"Jane\" }}\n", "Jane Doe\n"},
+		{"default", "{{ page.missing | default \"none\" }}|{{ page.title | default \"none\" }}\n", "none|Home\n"},
+		{"escape", "{{ \"<b>\" | escape }}\n", "&lt;b&gt;\n"},
+		{"safe", "{{ \"<b>\" | safe }}|{{ \"<i>\" | raw }}\n", "<b>|<i>\n"},
+		{"url_encode", "{{ \"a b&c\" | url_encode }}\n", "a+b%26c\n"},
+		{"slugify", "{{ \"Hello World\" | slugify }}\n", "hello-world\n"},
+		{"json", "{{ page.tags | json }}\n", "[&#34;go&#34;,&#34;web&#34;]\n"},
+		{"strip_html", "{{ \"<b>Tom</b> <i>Jerry</i>\" | strip_html }}\n", "Tom Jerry\n"},
+		{"strip_html entities", "{{ \"<b>Tom &amp; Jerry</b> &lt;3\" | strip_html }}\n", "Tom &amp; Jerry &lt;3\n"},
+		{"date", "{{ page.date_short | date \"Jan 2006\" }}\n", "Mar 2017\n"},
+		{"chained", "{{ page.author | lower | replace \" \" \"-\" }}\n", "john-doe\n"},
+	})
+}
+
+func TestRenderFilterArguments(t *testing.T) {
+	runRenderTests(t, []renderTest{
+		{"loop variable", "{% foreach site.posts as post %}{{ post.title | truncate loop.index \"\" }}|{% end foreach %}\n", "S|Fi|\n"},
+		{"variable", "{% set n = 2 %}\n{{ page.author | truncate n }}\n", "Jo...\n"},
+		{"calculation", "{% set n = 2 %}\n{{ page.author | truncate (n * 2) }}\n", "John...\n"},
+		{"property", "{{ page.missing | default page.author }}\n", "John Doe\n"},
+		{"call", "{{ page.missing | default first(site.posts).title }}\n", "Second\n"},
+	})
+}
+
+func TestRenderFilterErrors(t *testing.T) {
+	for _, template := range []string{
+		"{{ page.title | missing }}\n",
+		"{{ page.title | truncate }}\n",
+		"{{ page.title | truncate \"a\" }}\n",
+		"{{ page.title | truncate (1 + ) }}\n",
+		"{{ page.title | date \"Jan\" }}\n",
+	} {
+		renderError(t, template)
+	}
+}
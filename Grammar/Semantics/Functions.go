@@ -0,0 +1,487 @@
+package Semantics
+
+import (
+	"daphne/DataTypes"
+	"daphne/Errors"
+	"daphne/Grammar"
+	"daphne/Helpers"
+	"daphne/State"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// The type of an argument that can be any value
+const AnyValue = DataTypes.NullValue
+
+/**
+ * A function that can be called in templates, like post_asset("header.jpg")
+ */
+type Function struct {
+	Arguments []DataTypes.ValueKind // The type of every argument, arguments are converted to it if they can be
+	Optional  int                   // How many of the last arguments can be left out
+	Variadic  bool                  // The last argument can be given any number of times
+	Returns   DataTypes.ValueKind
+	Call      func(args []DataTypes.Value, ProgramState *State.CompilerState) (DataTypes.Value, Errors.Error)
+}
+
+var functions = make(map[string]Function)
+
+var absoluteURLRegex, _ = regexp.Compile("^[A-Za-z][A-Za-z0-9+.-]*:")
+
+// Names of the value types, for errors
+var kindNames = map[DataTypes.ValueKind]string{
+	AnyValue:              "value",
+	DataTypes.StringValue: "text",
+	DataTypes.NumberValue: "number",
+	DataTypes.BoolValue:   "bool",
+	DataTypes.DateValue:   "date",
+	DataTypes.ListValue:   "list",
+	DataTypes.MapValue:    "map",
+}
+
+/**
+ * Name.........: RegisterFunction
+ * Parameters...: name (string) - the name used in templates
+ *                function (Function) - the function
+ * Description..: Adds a function that can be called in templates, replaces any function with the same name
+ */
+func RegisterFunction(name string, function Function) {
+	functions[Helpers.ToLower(name)] = function
+}
+
+/**
+ * Name.........: EvaluateFunctionCall
+ * Parameters...: expression (string) - the call, name(argument, argument)
+ *                ProgramState (*State.CompilerState) - The program state
+ * Return.......: DataTypes.Value - what the function returned
+ *                Errors.Error - any errors
+ * Description..: Evaluates the arguments of a function call, converts them to the types the function takes,
+ *                and calls it
+ */
+func EvaluateFunctionCall(expression string, ProgramState *State.CompilerState) (DataTypes.Value, Errors.Error) {
+	_, name, exprs := Grammar.IsCall(expression)
+
+	function, exists := functions[Helpers.ToLower(name)]
+	if !exists {
+		return DataTypes.Value{}, Errors.NewFatal("Unknown function ", name)
+	}
+
+	// Every argument can be an expression of its own
+	args := []DataTypes.Value{}
+	for _, expr := range exprs {
+		arg, err := EvaluateValue(expr, ProgramState)
+		if err.HasError() {
+			return DataTypes.Value{}, Errors.NewFatal(err.Msg, " in the arguments of ", name)
+		}
+		args = append(args, arg)
+	}
+
+	args, err := convertArguments(name, function, args)
+	if err.HasError() {
+		return DataTypes.Value{}, err
+	}
+
+	result, err := function.Call(args, ProgramState)
+	if err.HasError() {
+		return DataTypes.Value{}, Errors.NewFatal("Function ", name, ": ", err.Msg)
+	}
+
+	if function.Returns != AnyValue && !result.IsNull() && result.Kind != function.Returns {
+		return DataTypes.Value{}, Errors.NewFatal("Function ", name, " returned a ", kindNames[result.Kind], " instead of a ", kindNames[function.Returns])
+	}
+
+	return result, Errors.None()
+}
+
+/**
+ * Name.........: convertArguments
+ * Parameters...: name (string) - the name of the function, for errors
+ *                function (Function) - the function
+ *                args ([]DataTypes.Value) - the evaluated arguments
+ * Return.......: []DataTypes.Value - the arguments as the types the function takes
+ *                Errors.Error - any errors
+ * Description..: Checks the number of arguments, and converts each one to its type
+ */
+func convertArguments(name string, function Function, args []DataTypes.Value) ([]DataTypes.Value, Errors.Error) {
+	min := len(function.Arguments) - function.Optional
+	max := len(function.Arguments)
+
+	if len(args) < min || (len(args) > max && !function.Variadic) {
+		expected := Helpers.ToStr(min)
+		if function.Variadic {
+			expected = "at least " + expected
+		} else if min != max {
+			expected = expected + " to " + Helpers.ToStr(max)
+		}
+
+		return nil, Errors.NewFatal("Function ", name, " takes ", expected, " argument(s), got ", Helpers.ToStr(len(args)))
+	}
+
+	converted := []DataTypes.Value{}
+	for i, arg := range args {
+		kind := function.Arguments[len(function.Arguments)-1]
+		if i < len(function.Arguments) {
+			kind = function.Arguments[i]
+		}
+
+		value, isValid := ConvertValue(arg, kind)
+		if !isValid {
+			return nil, Errors.NewFatal("Argument ", Helpers.ToStr(i+1), " of ", name, " has to be a ", kindNames[kind], ", got ", arg.String())
+		}
+		converted = append(converted, value)
+	}
+
+	return converted, Errors.None()
+}
+
+/**
+ * Name.........: ConvertValue
+ * Parameters...: value (DataTypes.Value) - the value to convert
+ *                kind (DataTypes.ValueKind) - the type to convert it to
+ * Return.......: DataTypes.Value - the converted value
+ *                bool - false if the value can not be converted
+ * Description..: Converts a value to another type, anything can be text or a bool, text can be a number or a
+ *                date if it looks like one, and a value that does not exist is an empty list or map
+ */
+func ConvertValue(value DataTypes.Value, kind DataTypes.ValueKind) (DataTypes.Value, bool) {
+	if kind == AnyValue || value.Kind == kind {
+		return value, true
+	}
+
+	switch kind {
+	case DataTypes.StringValue:
+		return DataTypes.Value{Kind: DataTypes.StringValue, Str: value.String(), Safe: value.Safe}, true
+
+	case DataTypes.BoolValue:
+		return DataTypes.NewBool(value.Truthy()), true
+
+	case DataTypes.NumberValue:
+		if num, isNum := value.AsNumber(); isNum {
+			return DataTypes.NewNumber(num), true
+		}
+
+	case DataTypes.DateValue:
+		if date, isDate := value.AsDate(); isDate {
+			return DataTypes.NewDate(date, ""), true
+		}
+
+	case DataTypes.ListValue:
+		if value.IsNull() {
+			return DataTypes.NewList([]DataTypes.Value{}), true
+		}
+
+	case DataTypes.MapValue:
+		if value.IsNull() {
+			return DataTypes.NewMap(make(map[string]DataTypes.Value)), true
+		}
+	}
+
+	return value, false
+}
+
+/**
+ * Built-in functions
+ */
+func init() {
+	// post_asset(file, file...) copies files from compiler.posts_asset_dir next to the post, and gives the first one
+	RegisterFunction("post_asset", Function{
+		Arguments: []DataTypes.ValueKind{DataTypes.StringValue},
+		Variadic:  true,
+		Returns:   DataTypes.StringValue,
+		Call: func(args []DataTypes.Value, ProgramState *State.CompilerState) (DataTypes.Value, Errors.Error) {
+			images := []string{}
+			for _, arg := range args {
+				images = append(images, Helpers.Trim(arg.String()))
+			}
+
+			// Register the function to move the image file after parsing
+			ProgramState.PerformAfterFileWrite = append(ProgramState.PerformAfterFileWrite, CopyPostAsset(images))
+
+			return DataTypes.NewString(images[0]), Errors.None()
+		},
+	})
+
+	// now() is the time the site is built
+	RegisterFunction("now", Function{
+		Returns: DataTypes.DateValue,
+		Call: func(args []DataTypes.Value, ProgramState *State.CompilerState) (DataTypes.Value, Errors.Error) {
+			return DataTypes.NewDate(time.Now(), ""), Errors.None()
+		},
+	})
+
+	// date_format(date, layout) formats a date with a Go time layout, like the date filter
+	RegisterFunction("date_format", Function{
+		Arguments: []DataTypes.ValueKind{DataTypes.DateValue, DataTypes.StringValue},
+		Returns:   DataTypes.StringValue,
+		Call: func(args []DataTypes.Value, ProgramState *State.CompilerState) (DataTypes.Value, Errors.Error) {
+			return DataTypes.NewString(args[0].Date.Format(args[1].String())), Errors.None()
+		},
+	})
+
+	// replace(text, search, replacement)
+	RegisterFunction("replace", Function{
+		Arguments: []DataTypes.ValueKind{DataTypes.StringValue, DataTypes.StringValue, DataTypes.StringValue},
+		Returns:   DataTypes.StringValue,
+		Call: func(args []DataTypes.Value, ProgramState *State.CompilerState) (DataTypes.Value, Errors.Error) {
+			return DataTypes.NewString(Helpers.Replace(args[0].String(), args[1].String(), args[2].String())), Errors.None()
+		},
+	})
+
+	// split(text, separator) makes a list
+	RegisterFunction("split", Function{
+		Arguments: []DataTypes.ValueKind{DataTypes.StringValue, DataTypes.StringValue},
+		Returns:   DataTypes.ListValue,
+		Call: func(args []DataTypes.Value, ProgramState *State.CompilerState) (DataTypes.Value, Errors.Error) {
+			items := []DataTypes.Value{}
+			if args[0].String() == "" {
+				return DataTypes.NewList(items), Errors.None()
+			}
+
+			for _, item := range strings.Split(args[0].String(), args[1].String()) {
+				items = append(items, DataTypes.NewString(item))
+			}
+			return DataTypes.NewList(items), Errors.None()
+		},
+	})
+
+	// join(list, separator) puts the items of a list together, ", " is the default separator
+	RegisterFunction("join", Function{
+		Arguments: []DataTypes.ValueKind{DataTypes.ListValue, DataTypes.StringValue},
+		Optional:  1,
+		Returns:   DataTypes.StringValue,
+		Call: func(args []DataTypes.Value, ProgramState *State.CompilerState) (DataTypes.Value, Errors.Error) {
+			separator := ", "
+			if len(args) > 1 {
+				separator = args[1].String()
+			}
+
+			items := []string{}
+			for _, item := range args[0].List {
+				items = append(items, item.String())
+			}
+			return DataTypes.NewString(strings.Join(items, separator)), Errors.None()
+		},
+	})
+
+	// contains(haystack, needle) works like the contains operator
+	RegisterFunction("contains", Function{
+		Arguments: []DataTypes.ValueKind{AnyValue, AnyValue},
+		Returns:   DataTypes.BoolValue,
+		Call: func(args []DataTypes.Value, ProgramState *State.CompilerState) (DataTypes.Value, Errors.Error) {
+			return DataTypes.NewBool(ContainsValue(args[0], args[1])), Errors.None()
+		},
+	})
+
+	// length(value) is the number of items in a list or map, or characters in text
+	RegisterFunction("length", Function{
+		Arguments: []DataTypes.ValueKind{AnyValue},
+		Returns:   DataTypes.NumberValue,
+		Call: func(args []DataTypes.Value, ProgramState *State.CompilerState) (DataTypes.Value, Errors.Error) {
+			switch args[0].Kind {
+			case DataTypes.NullValue:
+				return DataTypes.NewNumber(0), Errors.None()
+			case DataTypes.ListValue:
+				return DataTypes.NewNumber(float64(len(args[0].List))), Errors.None()
+			case DataTypes.MapValue:
+				return DataTypes.NewNumber(float64(len(args[0].Map))), Errors.None()
+			}
+			return DataTypes.NewNumber(float64(utf8.RuneCountInString(args[0].String()))), Errors.None()
+		},
+	})
+
+	// first(list) and last(list), nothing if the list is empty
+	RegisterFunction("first", Function{
+		Arguments: []DataTypes.ValueKind{DataTypes.ListValue},
+		Returns:   AnyValue,
+		Call: func(args []DataTypes.Value, ProgramState *State.CompilerState) (DataTypes.Value, Errors.Error) {
+			if len(args[0].List) == 0 {
+				return DataTypes.Value{}, Errors.None()
+			}
+			return args[0].List[0], Errors.None()
+		},
+	})
+
+	RegisterFunction("last", Function{
+		Arguments: []DataTypes.ValueKind{DataTypes.ListValue},
+		Returns:   AnyValue,
+		Call: func(args []DataTypes.Value, ProgramState *State.CompilerState) (DataTypes.Value, Errors.Error) {
+			if len(args[0].List) == 0 {
+				return DataTypes.Value{}, Errors.None()
+			}
+			return args[0].List[len(args[0].List)-1], Errors.None()
+		},
+	})
+
+	// slice(value, start, length) is part of a list or text, a negative start counts from the end
+	RegisterFunction("slice", Function{
+		Arguments: []DataTypes.ValueKind{AnyValue, DataTypes.NumberValue, DataTypes.NumberValue},
+		Optional:  1,
+		Returns:   AnyValue,
+		Call: func(args []DataTypes.Value, ProgramState *State.CompilerState) (DataTypes.Value, Errors.Error) {
+			size := len(args[0].List)
+			runes := []rune(args[0].String())
+			if args[0].Kind != DataTypes.ListValue {
+				size = len(runes)
+			}
+
+			start := int(args[1].Num)
+			if start < 0 {
+				start = size + start
+			}
+			start = clamp(start, 0, size)
+
+			end := size
+			if len(args) > 2 {
+				if args[2].Num < 0 {
+					return DataTypes.Value{}, Errors.NewFatal("the length can not be negative")
+				}
+				end = clamp(start+int(args[2].Num), start, size)
+			}
+
+			if args[0].Kind == DataTypes.ListValue {
+				return DataTypes.NewList(args[0].List[start:end]), Errors.None()
+			}
+			return DataTypes.NewString(string(runes[start:end])), Errors.None()
+		},
+	})
+
+	// where(list, key, value) keeps the items with a key equal to the value, compared like ==
+	RegisterFunction("where", Function{
+		Arguments: []DataTypes.ValueKind{DataTypes.ListValue, DataTypes.StringValue, AnyValue},
+		Returns:   DataTypes.ListValue,
+		Call: func(args []DataTypes.Value, ProgramState *State.CompilerState) (DataTypes.Value, Errors.Error) {
+			items := []DataTypes.Value{}
+			for _, item := range args[0].List {
+				if CompareValues(propertyPath(item, args[1].String()), args[2]) == 0 {
+					items = append(items, item)
+				}
+			}
+			return DataTypes.NewList(items), Errors.None()
+		},
+	})
+
+	// group_by(list, key) groups the items by a key, each group has a name, its items and their size
+	RegisterFunction("group_by", Function{
+		Arguments: []DataTypes.ValueKind{DataTypes.ListValue, DataTypes.StringValue},
+		Returns:   DataTypes.ListValue,
+		Call: func(args []DataTypes.Value, ProgramState *State.CompilerState) (DataTypes.Value, Errors.Error) {
+			names := []DataTypes.Value{}
+			groups := make(map[string][]DataTypes.Value)
+
+			// The groups are in the order their first item is in
+			for _, item := range args[0].List {
+				name := propertyPath(item, args[1].String())
+				if _, exists := groups[name.String()]; !exists {
+					names = append(names, name)
+				}
+				groups[name.String()] = append(groups[name.String()], item)
+			}
+
+			result := []DataTypes.Value{}
+			for _, name := range names {
+				items := groups[name.String()]
+				result = append(result, DataTypes.NewMap(map[string]DataTypes.Value{
+					"name":  name,
+					"items": DataTypes.NewList(items),
+					"size":  DataTypes.NewNumber(float64(len(items))),
+				}))
+			}
+			return DataTypes.NewList(result), Errors.None()
+		},
+	})
+
+	// relative_url(path) puts site.baseurl in front of a path, absolute_url(path) also puts site.url in front of that
+	RegisterFunction("relative_url", Function{
+		Arguments: []DataTypes.ValueKind{DataTypes.StringValue},
+		Returns:   DataTypes.StringValue,
+		Call: func(args []DataTypes.Value, ProgramState *State.CompilerState) (DataTypes.Value, Errors.Error) {
+			return DataTypes.NewString(relativeURL(args[0].String(), ProgramState)), Errors.None()
+		},
+	})
+
+	RegisterFunction("absolute_url", Function{
+		Arguments: []DataTypes.ValueKind{DataTypes.StringValue},
+		Returns:   DataTypes.StringValue,
+		Call: func(args []DataTypes.Value, ProgramState *State.CompilerState) (DataTypes.Value, Errors.Error) {
+			path := relativeURL(args[0].String(), ProgramState)
+			if isAbsoluteURL(path) {
+				return DataTypes.NewString(path), Errors.None()
+			}
+			return DataTypes.NewString(strings.TrimRight(ProgramState.Setting("site.url"), "/") + path), Errors.None()
+		},
+	})
+
+	// markdownify(text) converts Markdown to HTML, which is printed without escaping it
+	RegisterFunction("markdownify", Function{
+		Arguments: []DataTypes.ValueKind{DataTypes.StringValue},
+		Returns:   DataTypes.StringValue,
+		Call: func(args []DataTypes.Value, ProgramState *State.CompilerState) (DataTypes.Value, Errors.Error) {
+			return DataTypes.NewSafeString(Markdownify(args[0].String())), Errors.None()
+		},
+	})
+}
+
+/**
+ * Name.........: propertyPath
+ * Parameters...: value (DataTypes.Value) - a map or list
+ *                path (string) - the key, with dots for keys of keys
+ * Return.......: DataTypes.Value - the value at the key, null if there is none
+ * Description..: Gets a key of a value, like date_year of a post or author.name
+ */
+func propertyPath(value DataTypes.Value, path string) DataTypes.Value {
+	for _, key := range Helpers.Split(Helpers.ToLower(path), ".") {
+		value = value.Property(key)
+	}
+
+	return value
+}
+
+/**
+ * Name.........: relativeURL
+ * Parameters...: path (string) - a path on the site
+ *                ProgramState (*State.CompilerState) - The program state
+ * Return.......: string - the path with site.baseurl in front of it
+ * Description..: Makes a path relative to the root of the site, URLs with a scheme are left alone
+ */
+func relativeURL(path string, ProgramState *State.CompilerState) string {
+	if isAbsoluteURL(path) {
+		return path
+	}
+
+	path = "/" + strings.TrimLeft(path, "/")
+	if base := strings.Trim(ProgramState.Setting("site.baseurl"), "/"); base != "" {
+		path = "/" + base + path
+	}
+
+	return path
+}
+
+/**
+ * Name.........: isAbsoluteURL
+ * Parameters...: url (string) - the URL
+ * Return.......: bool
+ * Description..: True if the URL has a scheme (https:, mailto:) or starts with //
+ */
+func isAbsoluteURL(url string) bool {
+	return strings.HasPrefix(url, "//") || absoluteURLRegex.MatchString(url)
+}
+
+/**
+ * Name.........: clamp
+ * Parameters...: value (int) - the number
+ *                min (int) - the smallest it can be
+ *                max (int) - the largest it can be
+ * Return.......: int
+ * Description..: Keeps a number between two others
+ */
+func clamp(value int, min int, max int) int {
+	if value < min {
+		return min
+	} else if value > max {
+		return max
+	}
+
+	return value
+}
@@ -0,0 +1,129 @@
+package Semantics_test
+
+import (
+	"daphne/DataTypes"
+	"daphne/Errors"
+	"daphne/Grammar/Semantics"
+	"daphne/State"
+	"strings"
+	"testing"
+)
+
+func TestRenderFunctions(t *testing.T) {
+	runRenderTests(t, []renderTest{
+		{"post_asset", "<img src=\"{{ post_asset(\"header.jpg\", \"footer.jpg\") }}\">\n", "<img src=\"header.jpg\">\n"},
+		{"now", "{{ now() > 2017-01-01 }}|{{ date_format(now(), \"2006\") >= 2017 }}\n", "true|true\n"},
+		{"date_format", "{{ date_format(page.date_short, \"January 2, 2006\") }}|{{ date_format(\"2017-01-05\", \"02/01\") }}\n", "March 1, 2017|05/01\n"},
+		{"replace", "{{ replace(page.author, \"o\", \"0\") }}\n", "J0hn D0e\n"},
+		{"split", "{% foreach split(\"a/b/c\", \"/\") as part %}[{{ part }}]{% end foreach %}|{{ split(\"\", \",\").length }}\n", "[a][b][c]|0\n"},
+		{"join", "{{ join(page.tags, \" + \") }}|{{ join(page.tags) }}|[{{ join(page.missing) }}]\n", "go + web|go, web|[]\n"},
+		{"contains", "{{ contains(page.tags, \"go\") }}|{{ contains(page.author, \"Doe\") }}|{{ contains(page.tags, \"rust\") }}\n", "true|true|false\n"},
+		{"length", "{{ length(site.posts) }}|{{ length(page.author) }}|{{ length(page.missing) }}|{{ length(\"héllo\") }}\n", "2|8|0|5\n"},
+		{"first", "{{ first(page.tags) }}|[{{ first(page.missing) }}]\n", "go|[]\n"},
+		{"last", "{{ last(page.tags) }}|{{ last(site.posts).title }}\n", "web|First\n"},
+		{"slice", "{{ slice(page.author, 0, 4) }}|{{ slice(page.author, -3) }}|{{ join(slice([1, 2, 3, 4], 1, 2)) }}|[{{ slice(page.author, 20) }}]\n", "John|Doe|2, 3|[]\n"},
+		{"where", "{% foreach where(site.posts, \"title\", \"First\") as post %}{{ post.url }}{% end foreach %}|{{ length(where(site.posts, \"date_short\", 2017-02-01)) }}\n", "/blog/first|1\n"},
+		{"group_by", "{% foreach group_by(site.posts, \"date_short\") as group %}{{ group.name }}: {{ group.size }} {{ group.items.0.title }} {% end foreach %}\n", "2017-02-01: 1 Second 2017-01-01: 1 First \n"},
+		{"relative_url", "{{ relative_url(\"blog/\") }}|{{ relative_url(\"/about\") }}|{{ relative_url(\"https://example.org/\") }}\n", "/blog/|/about|https://example.org/\n"},
+		{"absolute_url", "{{ absolute_url(\"blog/\") }}|{{ absolute_url(page.url) }}|{{ absolute_url(\"mailto:a@b.c\") }}\n", "http://example.com/blog/|http://example.com/|mailto:a@b.c\n"},
+		{"markdownify", "{% capture text %}\n# Hi\n\nSome **bold** and [a link](/about).\n{% end capture %}\n{{ markdownify(text) }}\n", "<h1>Hi</h1>\n<p>Some <strong>bold</strong> and <a href=\"/about\">a link</a>.</p>\n"},
+	})
+}
+
+func TestRenderMarkdownifyEscaping(t *testing.T) {
+	runRenderTests(t, []renderTest{
+		{"html in the text", "{{ markdownify(\"<script>alert(1)</script> & <b>\") }}\n", "<p>&lt;script&gt;alert(1)&lt;/script&gt; &amp; &lt;b&gt;</p>\n"},
+		{"html in a heading", "{{ markdownify(\"## <i>Title</i>\") }}\n", "<h2>&lt;i&gt;Title&lt;/i&gt;</h2>\n"},
+		{"code", "{{ markdownify(\"Use `<br>` here\") }}\n", "<p>Use <code>&lt;br&gt;</code> here</p>\n"},
+		{"fenced code", "{% capture text %}\n```\n{{ \"<div>\" | raw }}\n```\n{% end capture %}\n{{ markdownify(text) }}\n", "<pre><code>&lt;div&gt;</code></pre>\n"},
+		{"javascript link", "{{ markdownify(\"[click](javascript:void)\") }}\n", "<p><a href=\"#\">click</a></p>\n"},
+		{"javascript image", "{{ markdownify(\"![x](javascript:void)\") }}\n", "<p><img src=\"#\" alt=\"x\"></p>\n"},
+		{"quotes in a link", "{{ markdownify('[a](/x\"onclick=\"y)') }}\n", "<p><a href=\"/x&#34;onclick=&#34;y\">a</a></p>\n"},
+		{"from a variable", "{% set text = \"<em>hi</em> *there*\" %}{{ markdownify(text) }}\n", "<p>&lt;em&gt;hi&lt;/em&gt; <em>there</em></p>\n"},
+		{"not escaped again", "<div>{{ markdownify(\"a & b\") }}</div>\n", "<div><p>a &amp; b</p></div>\n"},
+	})
+}
+
+func TestRenderCallProperties(t *testing.T) {
+	runRenderTests(t, []renderTest{
+		{"first", "{{ first(site.posts).title }}\n", "Second\n"},
+		{"last", "{{ last(site.posts).url }}\n", "/blog/first\n"},
+		{"index", "{{ split(\"a,b,c\", \",\").1 }}\n", "b\n"},
+		{"length", "{{ split(\"a,b,c\", \",\").length }}\n", "3\n"},
+		{"nested", "{{ first(group_by(site.posts, \"title\")).items.0.title }}\n", "Second\n"},
+		{"missing property", "[{{ first(site.posts).missing }}]\n", "[]\n"},
+		{"filter", "{{ first(site.posts).title | upper }}\n", "SECOND\n"},
+		{"calculation", "{{ split(\"a,b,c\", \",\").length - 1 }}\n", "2\n"},
+		{"condition", "{% if first(site.posts).title == \"Second\" %}yes{% end if %}\n", "yes\n"},
+		{"set", "{% set newest = first(site.posts).title %}\n{{ newest }}\n", "Second\n"},
+	})
+}
+
+func TestRenderCallPropertyErrors(t *testing.T) {
+	for _, template := range []string{
+		"{{ first(site.posts)title }}\n",
+		"{{ first(site.posts).title. }}\n",
+		"{{ missing(site.posts).title }}\n",
+	} {
+		renderError(t, template)
+	}
+}
+
+func TestRenderUnknownFunctions(t *testing.T) {
+	for _, template := range []string{
+		"{{ frobnicate(page.title) }}\n",
+		"{{ frobnicate() }}\n",
+		"{{ upper(first(site.posts).title) }}\n",
+		"{% if frobnicate(page.title) %}yes{% end if %}\n",
+		"{% set x = frobnicate(page.title) %}\n",
+		"{% foreach frobnicate(site.posts) as post %}{% end foreach %}\n",
+		"{{ page.title | default frobnicate() }}\n",
+		"{{ first(frobnicate(site.posts)) }}\n",
+		"{{ \"a\" + frobnicate(page.title) }}\n",
+	} {
+		if msg := renderError(t, template); !strings.Contains(msg, "Unknown function") {
+			t.Errorf("rendering %q: expected an unknown function error, got %q", template, msg)
+		}
+	}
+}
+
+func TestRenderFunctionArgumentErrors(t *testing.T) {
+	tests := []struct {
+		template string
+		expected string
+	}{
+		{"{{ first() }}\n", "Function first takes 1 argument(s), got 0"},
+		{"{{ first(site.posts, site.posts) }}\n", "Function first takes 1 argument(s), got 2"},
+		{"{{ post_asset() }}\n", "Function post_asset takes at least 1 argument(s), got 0"},
+		{"{{ slice(page.tags, \"a\") }}\n", "Argument 2 of slice has to be a number, got a"},
+		{"{{ date_format(\"soon\", \"2006\") }}\n", "Argument 1 of date_format has to be a date, got soon"},
+	}
+
+	for _, test := range tests {
+		if msg := renderError(t, test.template); !strings.Contains(msg, test.expected) {
+			t.Errorf("rendering %q: expected the error %q, got %q", test.template, test.expected, msg)
+		}
+	}
+}
+
+func TestRenderRegisteredFunction(t *testing.T) {
+	Semantics.RegisterFunction("test_repeat", Semantics.Function{
+		Arguments: []DataTypes.ValueKind{DataTypes.StringValue, DataTypes.NumberValue},
+		Optional:  1,
+		Returns:   DataTypes.StringValue,
+		Call: func(args []DataTypes.Value, ProgramState *State.CompilerState) (DataTypes.Value, Errors.Error) {
+			count := 2
+			if len(args) > 1 {
+				count = int(args[1].Num)
+			}
+			return DataTypes.NewString(strings.Repeat(args[0].String(), count)), Errors.None()
+		},
+	})
+
+	runRenderTests(t, []renderTest{
+		{"default argument", "{{ test_repeat(\"ab\") }}\n", "abab\n"},
+		{"text converted to a number", "{{ test_repeat(\"ab\", \"3\") }}\n", "ababab\n"},
+		{"expression arguments", "{{ test_repeat(page.title + \"!\", 1 + 1) }}\n", "Home!Home!\n"},
+		{"case of the name", "{{ TEST_REPEAT(\"a\", 1) }}\n", "a\n"},
+	})
+}
@@ -0,0 +1,178 @@
+package Semantics
+
+import (
+	"daphne/DataTypes"
+	"daphne/Errors"
+	"daphne/FileSystem"
+	"daphne/Grammar"
+	"daphne/Helpers"
+	"daphne/State"
+	"strings"
+)
+
+/**
+ * Name.........: evaluateImport
+ * Parameters...: cmd (DataTypes.ImportNode) - the import command
+ *                ProgramState (*State.CompilerState) - The program state
+ * Return.......: Errors.Error - any errors
+ * Description..: Makes the macros of a file from the include directory available, as alias.name if the import has an alias.
+ *                Inside of the file they can still call each other by name
+ */
+func evaluateImport(cmd DataTypes.ImportNode, ProgramState *State.CompilerState) Errors.Error {
+	file := ProgramState.Include(cmd.File)
+
+	contents, err := FileSystem.ReadFile(file)
+	if err.HasError() {
+		return err
+	}
+
+	nodes, err := Grammar.ParseTemplate(Helpers.Join(contents, "\n")+"\n", ProgramState.Syntax())
+	if err.HasError() {
+		return Errors.NewFatal(file, ": ", err.Msg)
+	}
+
+	// Only the macros are taken from the file, nothing in it is printed
+	for _, node := range nodes {
+		if macro, isMacro := node.(DataTypes.MacroNode); isMacro {
+			name := macro.Name
+			if cmd.Alias != "" {
+				name = cmd.Alias + "." + name
+			}
+			macro.Namespace = cmd.Alias
+			ProgramState.Macros[name] = macro
+		}
+	}
+
+	return Errors.None()
+}
+
+/**
+ * Name.........: IsMacroCall
+ * Parameters...: expression (string) - the expression to check
+ *                ProgramState (*State.CompilerState) - The program state
+ * Return.......: bool
+ * Description..: True if the expression calls a macro that was defined or imported
+ */
+func IsMacroCall(expression string, ProgramState *State.CompilerState) bool {
+	isCall, name, _ := Grammar.IsCall(expression)
+	if !isCall {
+		return false
+	}
+
+	_, exists := FindMacro(name, ProgramState)
+	return exists
+}
+
+/**
+ * Name.........: FindMacro
+ * Parameters...: name (string) - the name the macro is called with
+ *                ProgramState (*State.CompilerState) - The program state
+ * Return.......: DataTypes.MacroNode - the macro
+ *                bool - false if there is no macro with the name
+ * Description..: Finds a macro by name, a macro imported with an alias looks in its own file first
+ */
+func FindMacro(name string, ProgramState *State.CompilerState) (DataTypes.MacroNode, bool) {
+	name = Helpers.ToLower(name)
+
+	if ProgramState.Namespace != "" {
+		if macro, exists := ProgramState.Macros[ProgramState.Namespace+"."+name]; exists {
+			return macro, true
+		}
+	}
+
+	macro, exists := ProgramState.Macros[name]
+	return macro, exists
+}
+
+/**
+ * Name.........: EvaluateMacroCall
+ * Parameters...: expression (string) - the call, name(argument, name=argument)
+ *                ProgramState (*State.CompilerState) - The program state
+ * Return.......: DataTypes.Value - what the macro printed
+ *                Errors.Error - any errors
+ * Description..: Evaluates the arguments of a macro call, and evaluates the macro with them. The macro only
+ *                sees its parameters and the page, not the variables where it is called
+ */
+func EvaluateMacroCall(expression string, ProgramState *State.CompilerState) (DataTypes.Value, Errors.Error) {
+	_, name, args := Grammar.IsCall(expression)
+	macro, _ := FindMacro(name, ProgramState)
+
+	scope := make(map[string]DataTypes.Value)
+	positional := 0
+
+	for _, arg := range args {
+		isNamed, param, value := Grammar.SplitNamedArgument(arg)
+
+		if !isNamed {
+			if positional >= len(macro.Parameters) {
+				return DataTypes.Value{}, Errors.NewFatal("Too many arguments for the macro ", name, ", it takes ", Helpers.ToStr(len(macro.Parameters)))
+			}
+			param = macro.Parameters[positional].Name
+			positional++
+		} else if !hasParameter(macro, param) {
+			return DataTypes.Value{}, Errors.NewFatal("The macro ", name, " has no parameter ", param)
+		}
+
+		if _, exists := scope[param]; exists {
+			return DataTypes.Value{}, Errors.NewFatal("The parameter ", param, " of the macro ", name, " is given twice")
+		}
+
+		eval, err := EvaluateValue(value, ProgramState)
+		if err.HasError() {
+			return DataTypes.Value{}, err
+		}
+		scope[param] = eval
+	}
+
+	// Everything that was not given gets its default
+	for _, param := range macro.Parameters {
+		if _, exists := scope[param.Name]; exists {
+			continue
+		}
+
+		if param.Default == "" {
+			return DataTypes.Value{}, Errors.NewFatal("Missing the argument ", param.Name, " for the macro ", name)
+		}
+
+		eval, err := EvaluateValue(param.Default, ProgramState)
+		if err.HasError() {
+			return DataTypes.Value{}, err
+		}
+		scope[param.Name] = eval
+	}
+
+	output := strings.Builder{}
+
+	namespace := ProgramState.Namespace
+	ProgramState.Namespace = macro.Namespace
+
+	ProgramState.Meta.Push(scope, DataTypes.MacroScope)
+	err := evaluateNodes(macro.Body, &output, ProgramState)
+	ProgramState.Meta.Pop()
+
+	ProgramState.Namespace = namespace
+
+	if err.HasError() {
+		return DataTypes.Value{}, Errors.NewFatal(err.Msg, " in the macro ", name)
+	}
+
+	// Like an include, the line break at the end belongs to the call, the values in it have been escaped already
+	return DataTypes.NewSafeString(Helpers.TrimSuffix(output.String(), "\n")), Errors.None()
+}
+
+/**
+ * Name.........: hasParameter
+ * Parameters...: macro (DataTypes.MacroNode) - the macro
+ *                name (string) - the name of the parameter
+ * Return.......: bool
+ * Description..: True if the macro has a parameter with the name
+ */
+func hasParameter(macro DataTypes.MacroNode, name string) bool {
+	for _, param := range macro.Parameters {
+		if param.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
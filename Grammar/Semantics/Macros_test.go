@@ -0,0 +1,38 @@
+package Semantics_test
+
+import (
+	"testing"
+)
+
+func TestRenderMacros(t *testing.T) {
+	runRenderTests(t, []renderTest{
+		{"call", "{% macro greet(name) %}Hi {{ name }}{% end macro %}\n{{ greet(\"Ann\") }}\n", "\nHi Ann\n"},
+		{"default", "{% macro greet(name=\"you\") %}Hi {{ name }}{% end macro %}\n{{ greet() }}\n", "\nHi you\n"},
+		{"named argument", "{% macro link(url, text) %}{{ text }}:{{ url }}{% end macro %}\n{{ link(text=\"Home\", url=\"/\") }}\n", "\nHome:/\n"},
+		{"calls another macro", "{% macro a() %}A{{ b() }}{% end macro %}\n{% macro b() %}B{% end macro %}\n{{ a() }}\n", "\n\nAB\n"},
+	})
+}
+
+func TestRenderImportedMacros(t *testing.T) {
+	inTempDir(t)
+	writeTestFile(t, "_includes\\forms.html", "{% macro input(name) %}<input name=\"{{ name }}\">{% end macro %}\n{% macro field(name) %}<label>{{ label(name) }}</label>{{ input(name) }}{% end macro %}\n{% macro label(text) %}{{ text | upper }}{% end macro %}\n")
+
+	runRenderTests(t, []renderTest{
+		{"without an alias", "{% import forms.html %}\n{{ field(\"q\") }}\n", "<label>Q</label><input name=\"q\">\n"},
+		{"with an alias", "{% import forms.html as forms %}\n{{ forms.input(\"q\") }}\n", "<input name=\"q\">\n"},
+		{"sibling by its name", "{% import forms.html as forms %}\n{{ forms.field(\"q\") }}\n", "<label>Q</label><input name=\"q\">\n"},
+		{"sibling before the page", "{% import forms.html as forms %}\n{% macro label(text) %}page {{ text }}{% end macro %}\n{{ forms.field(\"q\") }} {{ label(\"q\") }}\n", "\n<label>Q</label><input name=\"q\"> page q\n"},
+		{"page macro from a sibling", "{% import forms.html as forms %}\n{% macro input(name) %}page{% end macro %}\n{{ forms.field(\"q\") }}\n", "\n<label>Q</label><input name=\"q\">\n"},
+	})
+
+	// The page still has to use the alias
+	renderError(t, "{% import forms.html as forms %}\n{{ field(\"q\") }}\n")
+}
+
+func TestRenderMacroLoops(t *testing.T) {
+	runRenderTests(t, []renderTest{
+		{"no parent from the caller", "{% macro inner() %}{% foreach site.posts as inner %}[{{ loop.parent.index }}].{{ loop.index }} {% end foreach %}{% end macro %}\n{% foreach site.posts as post %}\n{{ inner() }}\n{% end foreach %}\n", "\n[].1 [].2 \n[].1 [].2 \n"},
+		{"no loop from the caller", "{% macro index() %}[{{ loop.index }}]{% end macro %}\n{% foreach site.posts as post %}\n{{ index() }}\n{% end foreach %}\n", "\n[]\n[]\n"},
+		{"parent inside of the macro", "{% macro grid() %}{% foreach site.posts as a %}{% foreach site.posts as b %}{{ loop.parent.index }}.{{ loop.index }} {% end foreach %}{% end foreach %}{% end macro %}\n{% foreach site.posts as post limit 1 %}\n{{ grid() }}\n{% end foreach %}\n", "\n1.1 1.2 2.1 2.2 \n"},
+	})
+}
@@ -0,0 +1,162 @@
+package Semantics
+
+import (
+	"daphne/Helpers"
+	"html"
+	"regexp"
+	"strings"
+)
+
+var headingRegex, _ = regexp.Compile("^(#{1,6})\\s+(.*?)\\s*#*$")
+var listItemRegex, _ = regexp.Compile("^([-*+]|[0-9]+\\.)\\s+(.*)$")
+var ruleRegex, _ = regexp.Compile("^(-\\s*){3,}$|^(\\*\\s*){3,}$|^(_\\s*){3,}$")
+var imageRegex, _ = regexp.Compile("!\\[([^\\]]*)\\]\\(([^)\\s]+)\\)")
+var linkRegex, _ = regexp.Compile("\\[([^\\]]+)\\]\\(([^)\\s]+)\\)")
+var boldRegex, _ = regexp.Compile("\\*\\*([^*]+)\\*\\*|__([^_]+)__")
+var italicRegex, _ = regexp.Compile("\\*([^*]+)\\*|\\b_([^_]+)_\\b")
+
+/**
+ * Name.........: Markdownify
+ * Parameters...: text (string) - Markdown
+ * Return.......: string - the text as HTML
+ * Description..: Converts the common parts of Markdown to HTML: headings, paragraphs, lists, quotes, code,
+ *                rules, links, images, bold and italic text. HTML in the text is escaped
+ */
+func Markdownify(text string) string {
+	lines := Helpers.Split(Helpers.Replace(text, "\r\n", "\n"), "\n")
+	result := []string{}
+
+	paragraph := []string{}
+	list := "" // ul or ol while inside of a list
+	quote := []string{}
+
+	// Ends whatever block is open
+	flush := func() {
+		if len(paragraph) > 0 {
+			result = append(result, "<p>"+markdownInline(Helpers.Join(paragraph, "\n"))+"</p>")
+			paragraph = []string{}
+		}
+		if list != "" {
+			result = append(result, "</"+list+">")
+			list = ""
+		}
+		if len(quote) > 0 {
+			result = append(result, "<blockquote>"+Markdownify(Helpers.Join(quote, "\n"))+"</blockquote>")
+			quote = []string{}
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " \t")
+		trimmed := Helpers.Trim(line)
+
+		// Fenced code is kept as it is
+		if strings.HasPrefix(trimmed, "```") {
+			flush()
+
+			code := []string{}
+			for i = i + 1; i < len(lines) && !strings.HasPrefix(Helpers.Trim(lines[i]), "```"); i++ {
+				code = append(code, html.EscapeString(lines[i]))
+			}
+			result = append(result, "<pre><code>"+Helpers.Join(code, "\n")+"</code></pre>")
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, ">") {
+			if len(quote) == 0 {
+				flush()
+			}
+			quote = append(quote, Helpers.Trim(trimmed[1:]))
+			continue
+		} else if len(quote) > 0 {
+			flush()
+		}
+
+		if trimmed == "" {
+			flush()
+		} else if matches := headingRegex.FindStringSubmatch(trimmed); matches != nil {
+			flush()
+			level := Helpers.ToStr(len(matches[1]))
+			result = append(result, "<h"+level+">"+markdownInline(matches[2])+"</h"+level+">")
+		} else if ruleRegex.MatchString(trimmed) {
+			flush()
+			result = append(result, "<hr>")
+		} else if matches := listItemRegex.FindStringSubmatch(trimmed); matches != nil {
+			kind := "ul"
+			if strings.HasSuffix(matches[1], ".") {
+				kind = "ol"
+			}
+
+			if list != kind {
+				flush()
+				list = kind
+				result = append(result, "<"+kind+">")
+			}
+			result = append(result, "<li>"+markdownInline(matches[2])+"</li>")
+		} else if list != "" && len(paragraph) == 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			// An indented line continues the last item of a list
+			last := len(result) - 1
+			result[last] = strings.TrimSuffix(result[last], "</li>") + " " + markdownInline(trimmed) + "</li>"
+		} else {
+			if list != "" {
+				flush()
+			}
+			paragraph = append(paragraph, trimmed)
+		}
+	}
+	flush()
+
+	return Helpers.Join(result, "\n")
+}
+
+/**
+ * Name.........: markdownInline
+ * Parameters...: text (string) - one block of Markdown
+ * Return.......: string - the text as HTML
+ * Description..: Converts code, images, links, bold and italic text, nothing inside of `code` is converted
+ */
+func markdownInline(text string) string {
+	parts := strings.Split(text, "`")
+	result := ""
+
+	for i, part := range parts {
+		part = html.EscapeString(part)
+
+		// Every other part is inside of backticks, as long as the last one is closed
+		if i%2 == 1 && i < len(parts)-1 {
+			result = result + "<code>" + part + "</code>"
+			continue
+		} else if i%2 == 1 {
+			part = "`" + part
+		}
+
+		part = imageRegex.ReplaceAllStringFunc(part, func(image string) string {
+			matches := imageRegex.FindStringSubmatch(image)
+			return "<img src=\"" + markdownURL(matches[2]) + "\" alt=\"" + matches[1] + "\">"
+		})
+		part = linkRegex.ReplaceAllStringFunc(part, func(link string) string {
+			matches := linkRegex.FindStringSubmatch(link)
+			return "<a href=\"" + markdownURL(matches[2]) + "\">" + matches[1] + "</a>"
+		})
+		part = boldRegex.ReplaceAllString(part, "<strong>$1$2</strong>")
+		part = italicRegex.ReplaceAllString(part, "<em>$1$2</em>")
+
+		result = result + part
+	}
+
+	return result
+}
+
+/**
+ * Name.........: markdownURL
+ * Parameters...: url (string) - the URL of a link or image, already escaped
+ * Return.......: string - the URL, or # if it is not safe
+ * Description..: Keeps javascript: and other unsafe URLs out of links and images
+ */
+func markdownURL(url string) string {
+	if !IsSafeURL(html.UnescapeString(url)) {
+		return "#"
+	}
+
+	return url
+}
@@ -2,309 +2,513 @@ package Semantics
 
 import (
 	"daphne/DataTypes"
+	"daphne/Errors"
 	"daphne/FileSystem"
 	"daphne/Grammar"
 	"daphne/Grammar/Operators"
 	"daphne/Helpers"
 	"daphne/State"
+	"math"
 	"regexp"
+	"strings"
 )
 
-var variableRegex, _ = regexp.Compile("^([a-z])+(\\.[a-z]+)+$")
+var variableRegex, _ = regexp.Compile("^[a-z_][a-z0-9_]*(\\.[a-z0-9_]+)+$")
+
+// The properties after a call, like .title or .tags.0
+var propertyRegex, _ = regexp.Compile("^(\\.[A-Za-z0-9_]+)+$")
+
+/**
+ * Name.........: EvaluateCondition
+ * Parameters...: condition (string) - the condition
+ *                ProgramState (*State.CompilerState) - The program state
+ * Return.......: bool - true if the condition is met
+ *                Errors.Error - any errors
+ * Description..: Parses and evaluates the condition of an if statement, where clause or ternary
+ */
+func EvaluateCondition(condition string, ProgramState *State.CompilerState) (bool, Errors.Error) {
+	expression, err := Grammar.ParseExpression(condition)
+	if err.HasError() {
+		return false, err
+	}
+
+	result, err := EvaluateExpression(expression, ProgramState)
+
+	return result.Truthy(), err
+}
 
 /**
- * Evaluates something to true when given the current state
+ * Name.........: EvaluateExpression
+ * Parameters...: expression (DataTypes.Expression) - the parsed expression
+ *                ProgramState (*State.CompilerState) - The program state
+ * Return.......: DataTypes.Value - the result
+ *                Errors.Error - any errors
+ * Description..: Evaluates a parsed expression, && and || only evaluate their right hand side when needed
  */
-func IsTrue(inp string, ProgramState *State.CompilerState) bool {
-	inp = Helpers.Trim(Helpers.ToLower(inp))
+func EvaluateExpression(expression DataTypes.Expression, ProgramState *State.CompilerState) (DataTypes.Value, Errors.Error) {
+	switch expr := expression.(type) {
+	case DataTypes.OperandExpression:
+		return EvaluateOperand(expr.Text, ProgramState)
+
+	case DataTypes.NotExpression:
+		operand, err := EvaluateExpression(expr.Operand, ProgramState)
+		return DataTypes.NewBool(!operand.Truthy()), err
+
+	case DataTypes.BinaryExpression:
+		lhs, err := EvaluateExpression(expr.Left, ProgramState)
+		if err.HasError() {
+			return lhs, err
+		}
+
+		// Short circuit the logical operators
+		if expr.Operator == Operators.Logical.And && !lhs.Truthy() {
+			return DataTypes.NewBool(false), Errors.None()
+		} else if expr.Operator == Operators.Logical.Or && lhs.Truthy() {
+			return DataTypes.NewBool(true), Errors.None()
+		}
+
+		rhs, err := EvaluateExpression(expr.Right, ProgramState)
+		if err.HasError() {
+			return rhs, err
+		}
+
+		if Operators.IsArithmeticOperator(expr.Operator) {
+			return EvaluateArithmetic(lhs, expr.Operator, rhs, expr.Text)
+		}
+
+		return DataTypes.NewBool(EvaluateOperator(lhs, expr.Operator, rhs)), Errors.None()
+	}
+
+	return DataTypes.Value{}, Errors.NewFatal("Unknown expression")
+}
+
+/**
+ * Name.........: EvaluateOperator
+ * Parameters...: lhs (DataTypes.Value) - the left hand side
+ *                operator (string) - a logical, comparison or text operator
+ *                rhs (DataTypes.Value) - the right hand side
+ * Return.......: bool
+ * Description..: Applies an operator to two values
+ */
+func EvaluateOperator(lhs DataTypes.Value, operator string, rhs DataTypes.Value) bool {
+	switch operator {
+	case Operators.Logical.And:
+		return lhs.Truthy() && rhs.Truthy()
+
+	case Operators.Logical.Or:
+		return lhs.Truthy() || rhs.Truthy()
+
+	case Operators.Comparison.Equal:
+		return CompareValues(lhs, rhs) == 0
+
+	case Operators.Comparison.NotEqual:
+		return CompareValues(lhs, rhs) != 0
+
+	case Operators.Comparison.LargerOrEqual:
+		return CompareValues(lhs, rhs) >= 0
+
+	case Operators.Comparison.SmallerOrEqual:
+		return CompareValues(lhs, rhs) <= 0
+
+	case Operators.Comparison.Larger:
+		return CompareValues(lhs, rhs) > 0
+
+	case Operators.Comparison.Smaller:
+		return CompareValues(lhs, rhs) < 0
+
+	case Operators.Text.In:
+		return ContainsValue(rhs, lhs)
+
+	case Operators.Text.Contains:
+		return ContainsValue(lhs, rhs)
 
-	if Grammar.IsLogic(inp) {
-		return EvaluateLogicalCondition(inp, ProgramState)
-	} else if Grammar.IsCondit(inp) {
-		// Evaluate equality
-		return EvaluateConditional(inp, ProgramState)
+	case Operators.Text.StartsWith:
+		return strings.HasPrefix(lhs.String(), rhs.String())
 
-	} else if Grammar.IsStringLit(inp) {
-		inp = Helpers.StripQuotes(inp)
+	case Operators.Text.EndsWith:
+		return strings.HasSuffix(lhs.String(), rhs.String())
 	}
 
-	if inp != "" {
-		// variable
-		val := ""
-		if ProgramState.Exists(inp) {
-			val = ProgramState.Get(inp)
+	return false
+}
+
+/**
+ * Name.........: EvaluateArithmetic
+ * Parameters...: lhs (DataTypes.Value) - the left hand side
+ *                operator (string) - an arithmetic operator
+ *                rhs (DataTypes.Value) - the right hand side
+ *                source (string) - the expression, for errors
+ * Return.......: DataTypes.Value - the result
+ *                Errors.Error - any errors
+ * Description..: Applies an arithmetic operator to two numbers, + joins the two values as text if either
+ *                of them is not a number. Text is never read as a number, so "2" + "3" is 23
+ */
+func EvaluateArithmetic(lhs DataTypes.Value, operator string, rhs DataTypes.Value, source string) (DataTypes.Value, Errors.Error) {
+	if lhs.Kind != DataTypes.NumberValue || rhs.Kind != DataTypes.NumberValue {
+		if operator == Operators.Arithmetic.Add {
+			return DataTypes.NewString(lhs.String() + rhs.String()), Errors.None()
+		}
+		return DataTypes.Value{}, Errors.NewFatal("Can only use ", operator, " on numbers in ", source)
+	}
+	lhsNum, rhsNum := lhs.Num, rhs.Num
+
+	result := 0.0
+	switch operator {
+	case Operators.Arithmetic.Add:
+		result = lhsNum + rhsNum
+	case Operators.Arithmetic.Subtract:
+		result = lhsNum - rhsNum
+	case Operators.Arithmetic.Multiply:
+		result = lhsNum * rhsNum
+	case Operators.Arithmetic.Divide, Operators.Arithmetic.Modulo:
+		if rhsNum == 0 {
+			return DataTypes.Value{}, Errors.NewFatal("Division by zero in ", source)
+		}
+
+		if operator == Operators.Arithmetic.Divide {
+			result = lhsNum / rhsNum
 		} else {
-			if variableRegex.MatchString(Helpers.Trim(Helpers.ToLower(inp))) {
-				return false
+			result = math.Mod(lhsNum, rhsNum)
+		}
+	}
+
+	// Keep 0.1 + 0.2 from printing as 0.30000000000000004
+	return DataTypes.NewNumber(math.Round(result*1e9) / 1e9), Errors.None()
+}
+
+/**
+ * Name.........: ContainsValue
+ * Parameters...: haystack (DataTypes.Value) - a list, map or text
+ *                needle (DataTypes.Value) - the value to look for
+ * Return.......: bool
+ * Description..: True if a list has an item equal to the needle, a map has the needle as a key,
+ *                or text contains the needle
+ */
+func ContainsValue(haystack DataTypes.Value, needle DataTypes.Value) bool {
+	switch haystack.Kind {
+	case DataTypes.ListValue:
+		for _, item := range haystack.List {
+			if CompareValues(item, needle) == 0 {
+				return true
 			}
 		}
+		return false
 
-		val = Helpers.Trim(Helpers.ToLower(val))
+	case DataTypes.MapValue:
+		_, exists := haystack.Map[needle.String()]
+		return exists
 
-		return val != "false" && val != "0" && val != ""
+	case DataTypes.NullValue:
+		return false
 	}
 
-	return false
+	return strings.Contains(haystack.String(), needle.String())
 }
 
 /**
  * Evaluates a Ternary Operator
  */
-func EvaluateTernary(ternary string, ProgramState *State.CompilerState) string {
+func EvaluateTernary(ternary string, ProgramState *State.CompilerState) (string, Errors.Error) {
 	ternary = Helpers.Trim(ternary)
+	err := Errors.None()
 
 	// Detect if it is a ternary operator or not
 	isTernary, condition, ifTrue, ifFalse := Grammar.IsTernary(ternary)
 
 	if !isTernary {
-		return ternary
+		return ternary, err
 	}
 
 	// It is a ternary, check if the true or false are ternarys, and keep going deeper
 	isFalseTernary, _, _, _ := Grammar.IsTernary(ifFalse)
 	if isFalseTernary {
-		ifFalse = EvaluateTernary(ifFalse, ProgramState) // Recursively solve this
+		ifFalse, err = EvaluateTernary(ifFalse, ProgramState) // Recursively solve this
+		if err.HasError() {
+			return "", err
+		}
 	}
 
 	// Now check if the true statement is a ternary
 	isTrueTernary, _, _, _ := Grammar.IsTernary(ifTrue)
 	if isTrueTernary {
-		ifTrue = EvaluateTernary(ifTrue, ProgramState) // Recursively solve
+		ifTrue, err = EvaluateTernary(ifTrue, ProgramState) // Recursively solve
+		if err.HasError() {
+			return "", err
+		}
 	}
 
 	// Now, check if the condition is a ternary
 	isConditionTernary, _, _, _ := Grammar.IsTernary(condition)
 	if isConditionTernary {
-		condition = EvaluateTernary(condition, ProgramState)
+		condition, err = EvaluateTernary(condition, ProgramState)
+		if err.HasError() {
+			return "", err
+		}
+	}
+
+	isTrue, err := EvaluateCondition(condition, ProgramState)
+	if err.HasError() {
+		return "", err
 	}
 
-	if IsTrue(condition, ProgramState) {
-		return ifTrue
+	if isTrue {
+		return ifTrue, err
 	} else {
-		return ifFalse
+		return ifFalse, err
 	}
 }
 
 /**
- * Evaluates a Conditional Expression
+ * Name.........: CompareValues
+ * Parameters...: lhs (DataTypes.Value) - the left hand side
+ *                rhs (DataTypes.Value) - the right hand side
+ * Return.......: int - negative if lhs is smaller, 0 if they are equal, positive if lhs is larger
+ * Description..: Compares two values by value when both sides are numbers (integers or decimals), both are
+ *                dates, or both are bools. Text that looks like a number or a date counts as one, everything
+ *                else is compared as text without case
  */
-func EvaluateConditional(condition string, ProgramState *State.CompilerState) bool {
-
-	compound, _, _, _ := Grammar.IsLogicalCondition(condition)
-	if compound {
-		return EvaluateLogicalCondition(condition, ProgramState)
+func CompareValues(lhs DataTypes.Value, rhs DataTypes.Value) int {
+	if lhsNum, isNum := lhs.AsNumber(); isNum {
+		if rhsNum, isNum := rhs.AsNumber(); isNum {
+			return compareNumbers(lhsNum, rhsNum)
+		}
 	}
 
-	isConditional, lhs, operator, rhs := Grammar.IsConditional(condition)
-	if !isConditional {
-		return false
+	if lhsDate, isDate := lhs.AsDate(); isDate {
+		if rhsDate, isDate := rhs.AsDate(); isDate {
+			if lhsDate.Before(rhsDate) {
+				return -1
+			} else if lhsDate.After(rhsDate) {
+				return 1
+			}
+			return 0
+		}
 	}
 
-	//Helpers.Print("magenta", lhs, operator, rhs)
-	lhs = Helpers.ToLower(EvaluateVariable(lhs, ProgramState))
-	rhs = Helpers.ToLower(EvaluateVariable(rhs, ProgramState))
-
-	switch operator {
-	case Operators.Comparison.Equal:
-		//Helpers.Print("green", lhs, " == ", rhs)
-		return lhs == rhs
-
-	case Operators.Comparison.NotEqual:
-		return lhs != rhs
-
-	case Operators.Comparison.LargerOrEqual:
-		return lhs >= rhs
-
-	case Operators.Comparison.SmallerOrEqual:
-		return lhs <= rhs
+	if lhs.Kind == DataTypes.BoolValue && rhs.Kind == DataTypes.BoolValue {
+		return compareNumbers(boolToNumber(lhs.Bool), boolToNumber(rhs.Bool))
+	}
 
-	case Operators.Comparison.Larger:
-		return lhs > rhs
+	lhsStr := Helpers.ToLower(lhs.String())
+	rhsStr := Helpers.ToLower(rhs.String())
 
-	case Operators.Comparison.Smaller:
-		return lhs < rhs
+	if lhsStr < rhsStr {
+		return -1
+	} else if lhsStr > rhsStr {
+		return 1
 	}
-
-	return false
+	return 0
 }
 
-/**
- * Evaluates a compound conditional
- */
-func EvaluateLogicalCondition(condition string, ProgramState *State.CompilerState) bool {
-	compound, lhs, operator, rhs := Grammar.IsLogicalCondition(condition)
-
-	if !compound {
-		return false
+func compareNumbers(lhs float64, rhs float64) int {
+	if lhs < rhs {
+		return -1
+	} else if lhs > rhs {
+		return 1
 	}
+	return 0
+}
 
-	lhsBool := IsTrue(lhs, ProgramState)
-	rhsBool := IsTrue(rhs, ProgramState)
-
-	switch operator {
-	case "||":
-		return lhsBool || rhsBool
-	case "&&":
-		return lhsBool && rhsBool
+func boolToNumber(b bool) float64 {
+	if b {
+		return 1
 	}
-
-	return false
+	return 0
 }
 
 /**
- * Evaluates a Variable
+ * Name.........: EvaluateSetCommand
+ * Parameters...: variable (string) - the variable to set
+ *                value (string) - the value to give it
+ *                global (bool) - set it for the whole page, not just the innermost scope
+ *                ProgramState (*State.CompilerState) - The program state
+ * Return.......: Errors.Error - any errors
+ * Description..: Evaluates a set command, the value can be anything a print can
  */
-func EvaluateSetCommand(variable string, value string, ProgramState *State.CompilerState) {
+func EvaluateSetCommand(variable string, value string, global bool, ProgramState *State.CompilerState) Errors.Error {
 	variable = Helpers.Trim(variable)
 
-	value = EvaluateVariable(value, ProgramState)
+	eval, err := EvaluateValue(value, ProgramState)
+	if err.HasError() {
+		return err
+	}
 
 	// Set the variable
-	ProgramState.Set(variable, value)
-	return
+	ProgramState.Set(variable, eval, global)
+	return Errors.None()
 }
 
 /**
  * Evaluates a Variable
  */
-func EvaluateVariable(variable string, ProgramState *State.CompilerState) string {
+func EvaluateVariable(variable string, ProgramState *State.CompilerState) DataTypes.Value {
 	variable = Helpers.Trim(variable)
 
-	if ProgramState.Exists(Helpers.ToLower(variable)) {
-		return ProgramState.Get(Helpers.ToLower(variable))
-	}
-
-	// Evaluate concatenation if there is any
-	tokens := Helpers.Split(variable, "+")
-	if len(tokens) > 1 {
-		eval := ""
-		for _, token := range tokens {
-			eval = eval + EvaluateVariable(token, ProgramState)
-		}
-		variable = eval
+	if ProgramState.Exists(variable) {
+		return ProgramState.Get(variable)
 	}
 
 	// Remove quotes from string literals
 	if Grammar.IsStringLit(variable) {
-		variable = Helpers.StripQuotes(variable)
-	} else {
-		if variableRegex.MatchString(Helpers.Trim(Helpers.ToLower(variable))) {
-			//Helpers.Print("red", "Not Found: ", variable)
-			return ""
-		}
+		return DataTypes.NewString(Helpers.StripQuotes(variable))
+	} else if variableRegex.MatchString(Helpers.ToLower(variable)) {
+		//Helpers.Print("red", "Not Found: ", variable)
+		return DataTypes.Value{}
 	}
 
-	return variable
+	// Anything else is a literal, numbers, true and false get their types
+	return DataTypes.ParseValue(variable)
 }
 
 /**
- * Name.........:
- * Parameters...:
- * Return.......: string - the evaluated print command
- * Description..: Evaluates a print command
+ * Name.........: EvaluateValue
+ * Parameters...: expression (string) - a value, followed by any filters
+ *                ProgramState (*State.CompilerState) - The program state
+ * Return.......: DataTypes.Value - the value, after it went through its filters
+ *                Errors.Error - any errors
+ * Description..: Evaluates the value of a print command or an argument
  */
-func EvaluatePrintCommand(command string, ProgramState *State.CompilerState) string {
-	result := Helpers.Trim(command[2 : len(command)-2]) // Remove tags
+func EvaluateValue(expression string, ProgramState *State.CompilerState) (DataTypes.Value, Errors.Error) {
+	pipeline := Grammar.SplitPipeline(expression)
+	result := pipeline[0]
+
+	eval := DataTypes.NewString("")
 
 	if result == "" {
-		return result
+		eval = DataTypes.NewString("")
+	} else if isCall, _, _ := Grammar.IsCall(result); isCall {
+		// A macro or a function
+		call, err := EvaluateOperand(result, ProgramState)
+		if err.HasError() {
+			return eval, err
+		}
+		eval = call
+	} else {
+		// Evaluate not as a function
+		value, err := EvaluateTernary(result, ProgramState)
+		if err.HasError() {
+			return eval, err
+		}
+
+		eval, err = EvaluateArithmeticValue(value, ProgramState)
+		if err.HasError() {
+			return eval, err
+		}
 	}
 
-	eval := ""
+	// Pass the value through every filter
+	for _, filter := range pipeline[1:] {
+		err := Errors.None()
 
-	// Check if a special function
-	if Grammar.IsSpecialFunc(result) {
-		// Evaluate as a function
-		eval = EvaluateFunction(result, ProgramState)
-	} else {
-		// Evaluate not as a function
-		eval = EvaluateVariable(EvaluateTernary(result, ProgramState), ProgramState)
+		eval, err = EvaluateFilter(eval, filter, ProgramState)
+		if err.HasError() {
+			return eval, err
+		}
 	}
 
-	return eval
+	return eval, Errors.None()
 }
 
 /**
- * Name.........: EvaluateFunction
- * Parameters...: line (string) - the function to validate
+ * Name.........: EvaluateRange
+ * Parameters...: start (string) - the first number
+ *                end (string) - the last number
  *                ProgramState (*State.CompilerState) - The program state
- * Return.......: string
- * Description..: Evaluates a function
+ * Return.......: DataTypes.Value - a list of every whole number from the start to the end
+ *                Errors.Error - any errors
+ * Description..: Evaluates a range like 1..5, both ends are included and it counts down when the start is
+ *                larger than the end
  */
-func EvaluateFunction(line string, ProgramState *State.CompilerState) string {
-	eval := ""
+func EvaluateRange(start string, end string, ProgramState *State.CompilerState) (DataTypes.Value, Errors.Error) {
+	bounds := []float64{}
 
-	isValid, funcName, funcParam := Grammar.IsSpecialFunction(line)
+	for _, expression := range []string{start, end} {
+		value, err := EvaluateArithmeticValue(expression, ProgramState)
+		if err.HasError() {
+			return DataTypes.Value{}, err
+		}
 
-	// Return the line if it is not a valid function
-	if !isValid {
-		return line
+		num, isNumber := value.AsNumber()
+		if !isNumber || num != math.Trunc(num) {
+			return DataTypes.Value{}, Errors.NewFatal("A range can only be between whole numbers, got ", expression)
+		}
+		bounds = append(bounds, num)
 	}
 
-	// Handle the function
-	switch funcName {
-	case "asset":
-		funcParams := Helpers.Split(funcParam, ",")
-		eval = Helpers.Trim(funcParams[0])
+	step := 1.0
+	if bounds[0] > bounds[1] {
+		step = -1
+	}
 
-		// Register the function to move the image file after parsing
-		ProgramState.PerformAfterFileWrite = append(ProgramState.PerformAfterFileWrite, CopyPostAsset(funcParams))
+	items := []DataTypes.Value{}
+	for num := bounds[0]; num != bounds[1]+step; num += step {
+		items = append(items, DataTypes.NewNumber(num))
 	}
 
-	return eval
+	return DataTypes.NewList(items), Errors.None()
 }
 
-func CopyPostAsset(images []string) State.SpecialFunction {
-	return func(page DataTypes.Page, ProgramState *State.CompilerState) {
-		// Copy all of the images
-		for _, img := range images {
-			dir := Helpers.Split(page.OutFile, "\\")
-			dest := Helpers.Join(dir[:len(dir)-1], "\\") + "\\" + img
-			Helpers.Print("white", ProgramState.Config["compiler.posts_asset_dir"]+"\\"+page.GetSlug()+"\\"+img)
-			// Copy the image into the path of the final post
-			err := FileSystem.CopyFile(ProgramState.Config["compiler.posts_asset_dir"]+"\\"+page.GetSlug()+"\\"+img, dest)
-			err.Handle()
-		}
+/**
+ * Name.........: EvaluateArithmeticValue
+ * Parameters...: value (string) - a value, a calculation like loop.index * 2 or site.url + "feed.xml", or a
+ *                                 condition like page.num == 10
+ *                ProgramState (*State.CompilerState) - The program state
+ * Return.......: DataTypes.Value - the result, conditions give true or false
+ *                Errors.Error - any errors
+ * Description..: Evaluates a value that can be a calculation or a condition, a single operand is evaluated as a
+ *                variable or literal
+ */
+func EvaluateArithmeticValue(value string, ProgramState *State.CompilerState) (DataTypes.Value, Errors.Error) {
+	expression, err := Grammar.ParseExpression(value)
+	if err.HasError() {
+		return DataTypes.Value{}, err
 	}
+
+	return EvaluateExpression(expression, ProgramState)
 }
 
 /**
- * Name.........:
- * Parameters...:
- * Return.......:
- * Description..:
+ * Name.........: EvaluateOperand
+ * Parameters...: operand (string) - a variable, literal, or a call of a macro or function
+ *                ProgramState (*State.CompilerState) - The program state
+ * Return.......: DataTypes.Value - the value
+ *                Errors.Error - any errors
+ * Description..: Evaluates one value of an expression, calling a function that does not exist is an error.
+ *                Properties of what a call returns can be used like first(site.posts).title
  */
-func GetCommand(cmd string) DataTypes.MultilineCommand {
-	cmd = Helpers.Strip(cmd) // Remove everything around the command
-
-	command := DataTypes.MultilineCommand{State: 0}
-
-	cmd = Helpers.Trim(cmd[2 : len(cmd)-2]) // Strip tags
+func EvaluateOperand(operand string, ProgramState *State.CompilerState) (DataTypes.Value, Errors.Error) {
+	if call, rest := Grammar.SplitCall(operand); call != "" && rest != "" {
+		if !propertyRegex.MatchString(rest) {
+			return DataTypes.Value{}, Errors.NewFatal("Unexpected ", rest, " after ", call)
+		}
 
-	tokens := Helpers.Split(cmd, " ")
+		value, err := EvaluateOperand(call, ProgramState)
+		if err.HasError() {
+			return value, err
+		}
 
-	if len(tokens) < 2 {
-		return command
+		return propertyPath(value, rest[1:]), Errors.None()
 	}
 
-	command.Control = tokens[0]
-	command.Condition = Helpers.Join(tokens[1:], " ")
+	if IsMacroCall(operand, ProgramState) {
+		return EvaluateMacroCall(operand, ProgramState)
+	} else if isCall, _, _ := Grammar.IsCall(operand); isCall {
+		return EvaluateFunctionCall(operand, ProgramState)
+	}
 
-	return command
+	return EvaluateVariable(operand, ProgramState), Errors.None()
 }
 
-/**
- * Name.........:
- * Parameters...:
- * Return.......:
- * Description..:
- */
-func ParseForEachCondition(condition string) (string, string) {
-	operands := Helpers.Split(condition, " as ")
-
-	if len(operands) < 2 {
-		return "", ""
+func CopyPostAsset(images []string) State.SpecialFunction {
+	return func(page DataTypes.Page, ProgramState *State.CompilerState) {
+		// Copy all of the images
+		for _, img := range images {
+			dir := Helpers.Split(page.OutFile, "\\")
+			dest := Helpers.Join(dir[:len(dir)-1], "\\") + "\\" + img
+			Helpers.Print("white", ProgramState.Setting("compiler.posts_asset_dir")+"\\"+page.GetSlug()+"\\"+img)
+			// Copy the image into the path of the final post
+			err := FileSystem.CopyFile(ProgramState.Setting("compiler.posts_asset_dir")+"\\"+page.GetSlug()+"\\"+img, dest)
+			err.Handle()
+		}
 	}
-
-	return Helpers.Trim(operands[0]), Helpers.Trim(operands[1])
 }
@@ -0,0 +1,261 @@
+package Semantics_test
+
+import (
+	"daphne/DataTypes"
+	"daphne/Grammar/Semantics"
+	"daphne/Parser"
+	"testing"
+)
+
+func TestRenderArithmetic(t *testing.T) {
+	runRenderTests(t, []renderTest{
+		{"add", "{% set n = 10 %}\n{{ n + 2 }}\n", "12\n"},
+		{"precedence", "{{ 2 + 3 * 4 }}\n", "14\n"},
+		{"parenthesis", "{{ (2 + 3) * 4 }}\n", "20\n"},
+		{"decimals", "{{ 0.1 + 0.2 }}\n", "0.3\n"},
+		{"string literals", "{{ \"2\" + \"3\" }}\n", "23\n"},
+		{"string and number", "{{ \"2\" + 3 }}\n", "23\n"},
+		{"number and string", "{% set n = 10 %}\n{{ n + \"px\" }}\n", "10px\n"},
+		{"text", "{{ page.title + \" page\" }}\n", "Home page\n"},
+	})
+}
+
+func TestRenderArithmeticErrors(t *testing.T) {
+	for _, template := range []string{
+		"{{ \"6\" - \"2\" }}\n",
+		"{{ \"6\" * 2 }}\n",
+		"{{ 6 / \"2\" }}\n",
+		"{{ \"6\" % \"4\" }}\n",
+		"{{ page.title * 2 }}\n",
+		"{{ 6 / 0 }}\n",
+	} {
+		renderError(t, template)
+	}
+}
+
+func TestRenderOperatorsWithoutWhitespace(t *testing.T) {
+	runRenderTests(t, []renderTest{
+		{"subtract", "{% set num = 10 %}\n{{ num -1 }}\n", "9\n"},
+		{"subtract without spaces", "{% set num = 10 %}\n{{ num-1 }}\n", "9\n"},
+		{"subtract numbers", "{{ 10-2 }}|{{ 10 -2 }}|{{ 10- 2 }}\n", "8|8|8\n"},
+		{"divide", "{{ 10/2 }}\n", "5\n"},
+		{"divide with a space", "{{ 10 /2 }}|{{ 10/ 2 }}\n", "5|5\n"},
+		{"negative number", "{{ -1 + 3 }}|{{ 2 * -3 }}|{{ 4 - -1 }}\n", "2|-6|5\n"},
+		{"date", "{{ 2017-01-01 }}\n", "2017-01-01\n"},
+		{"date in a condition", "{% if page.date_short > 2017-01-01 %}after{% end if %}\n", "after\n"},
+		{"path", "{{ /blog/my-post/ }}\n", "/blog/my-post/\n"},
+		{"url", "{{ https://example.com/a-b/c }}\n", "https://example.com/a-b/c\n"},
+		{"concatenate a path", "{{ site.url + /blog/ }}\n", "http://example.com//blog/\n"},
+	})
+}
+
+func TestRenderConditionPrints(t *testing.T) {
+	runRenderTests(t, []renderTest{
+		{"equal", "{% set num = 10 %}\n{{ num == 10 }}|{{ num == 11 }}\n", "true|false\n"},
+		{"compare", "{% set num = 10 %}\n{{ num > 2 }}|{{ num <= 2 }}\n", "true|false\n"},
+		{"not", "{{ !true }}|{{ !false }}|{{ not page.title }}\n", "false|true|false\n"},
+		{"and or", "{{ true && false }}|{{ false || true }}\n", "false|true\n"},
+		{"text operator", "{{ \"go\" in page.tags }}\n", "true\n"},
+		{"calculation", "{% set num = 10 %}\n{{ num * 2 == 20 }}\n", "true\n"},
+		{"set", "{% set big = 3 > 2 %}\n{% if big %}big{% end if %}\n", "big\n"},
+	})
+}
+
+func TestRenderExpressionErrors(t *testing.T) {
+	for _, template := range []string{
+		"{{ -page.title }}\n",
+		"{{ page.title = 1 }}\n",
+		"{{ page.title & 1 }}\n",
+		"{{ 10 / }}\n",
+		"{{ (1 + 2 }}\n",
+		"{{ two-column }}\n",
+	} {
+		if msg := renderError(t, template); msg == "" {
+			t.Errorf("expected an error message for %q", template)
+		}
+	}
+}
+
+func TestRenderLength(t *testing.T) {
+	runRenderTests(t, []renderTest{
+		{"collection", "{{ site.posts.length }}\n", "2\n"},
+		{"calculation", "{{ site.posts.length - 1 }}|{{ site.posts.length-1 }}\n", "1|1\n"},
+		{"meta", "{{ page.tags.length }}\n", "2\n"},
+		{"condition", "{% if page.tags.length > 1 %}tags{% end if %}\n", "tags\n"},
+		{"loop item", "{% foreach site.posts as post limit 1 %}{{ post.title }} {{ page.tags.length * 2 }}{% end foreach %}\n", "Second 4\n"},
+		{"set list", "{% set items = [a, b, c] %}\n{{ items.length }}\n", "3\n"},
+		{"last index", "{{ page.tags.length - 1 }}: {{ page.tags.1 }}\n", "1: web\n"},
+	})
+}
+
+func TestRenderCollectionOrder(t *testing.T) {
+	ProgramState := newTestState()
+
+	posts := []DataTypes.Page{
+		newTestPage("Old", "/blog/old", "2016-12-31"),
+		newTestPage("Bravo", "/blog/bravo", "2017-02-01"),
+		newTestPage("Alpha", "/blog/alpha", "2017-02-01"),
+		newTestPage("New", "/blog/new", "2017-10-02"),
+		newTestPage("Middle", "/blog/middle", "2017-09-15"),
+	}
+
+	pages := []DataTypes.Page{
+		newTestPage("Contact", "/contact", "2017-01-01"),
+		newTestPage("About", "/about", "2017-01-01"),
+		newTestPage("Home", "/", "2017-01-01"),
+		newTestPage("Blog", "/blog", "2017-01-01"),
+		newTestPage("Archive", "/archive", "2017-01-01"),
+		newTestPage("Team", "/team", "2017-01-01"),
+	}
+	pages[0].Meta["page.weight"] = DataTypes.NewNumber(30)
+	pages[2].Meta["page.weight"] = DataTypes.NewNumber(-1)
+	pages[3].Meta["page.order"] = DataTypes.NewNumber(10)
+	pages[4].Meta["page.weight"] = DataTypes.NewNumber(10)
+	pages[5].Meta["page.weight"] = DataTypes.NewString("9")
+
+	ProgramState.Special = map[string][]DataTypes.Page{"site.posts": posts, "site.pages": pages}
+	Parser.SortCollections(ProgramState)
+
+	tests := []renderTest{
+		{"posts newest first, then by file", "{% foreach site.posts as post %}{{ post.title }} {% end foreach %}\n", "New Middle Alpha Bravo Old \n"},
+		{"pages by weight, then by file, then without one", "{% foreach site.pages as nav %}{{ nav.title }} {% end foreach %}\n", "Home Team Archive Blog Contact About \n"},
+		{"where keeps the order", "{% foreach site.posts as post where post.date_short > 2017-01-01 %}{{ post.title }} {% end foreach %}\n", "New Middle Alpha Bravo \n"},
+		{"loop index follows the order", "{% foreach site.posts as post limit 2 %}{{ loop.index }}.{{ post.title }} {% end foreach %}\n", "1.New 2.Middle \n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if result := render(t, ProgramState, test.template); result != test.expected {
+				t.Errorf("rendering %q\n got: %q\nwant: %q", test.template, result, test.expected)
+			}
+		})
+	}
+}
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		raw     string
+		kind    DataTypes.ValueKind
+		printed string
+	}{
+		{"hello", DataTypes.StringValue, "hello"},
+		{"\"10\"", DataTypes.StringValue, "10"},
+		{"10", DataTypes.NumberValue, "10"},
+		{"-2.50", DataTypes.NumberValue, "-2.50"},
+		{"True", DataTypes.BoolValue, "true"},
+		{"false", DataTypes.BoolValue, "false"},
+		{"2017-01-02", DataTypes.DateValue, "2017-01-02"},
+		{"January 2, 2017", DataTypes.DateValue, "January 2, 2017"},
+		{"[go, \"a, b\", 3]", DataTypes.ListValue, "go, a, b, 3"},
+		{"[]", DataTypes.ListValue, ""},
+		{"  padded  ", DataTypes.StringValue, "padded"},
+	}
+
+	for _, test := range tests {
+		value := DataTypes.ParseValue(test.raw)
+		if value.Kind != test.kind || value.String() != test.printed {
+			t.Errorf("ParseValue(%q): got kind %d printed as %q, want kind %d printed as %q", test.raw, value.Kind, value.String(), test.kind, test.printed)
+		}
+	}
+
+	if list := DataTypes.ParseValue("[1, [2, 3]]"); len(list.List) != 2 || list.List[1].Kind != DataTypes.ListValue || list.List[0].Kind != DataTypes.NumberValue {
+		t.Errorf("ParseValue of a nested list gave %#v", list)
+	}
+}
+
+func TestConvertValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   DataTypes.Value
+		kind    DataTypes.ValueKind
+		valid   bool
+		printed string
+	}{
+		{"number to text", DataTypes.NewNumber(2.5), DataTypes.StringValue, true, "2.5"},
+		{"text to number", DataTypes.NewString(" 42 "), DataTypes.NumberValue, true, "42"},
+		{"word to number", DataTypes.NewString("many"), DataTypes.NumberValue, false, "many"},
+		{"text to date", DataTypes.NewString("2017-03-01"), DataTypes.DateValue, true, "March 1, 2017"},
+		{"word to date", DataTypes.NewString("soon"), DataTypes.DateValue, false, "soon"},
+		{"text to bool", DataTypes.NewString("x"), DataTypes.BoolValue, true, "true"},
+		{"empty text to bool", DataTypes.NewString(""), DataTypes.BoolValue, true, "false"},
+		{"zero to bool", DataTypes.NewNumber(0), DataTypes.BoolValue, true, "false"},
+		{"null to list", DataTypes.Value{}, DataTypes.ListValue, true, ""},
+		{"null to map", DataTypes.Value{}, DataTypes.MapValue, true, ""},
+		{"text to list", DataTypes.NewString("a, b"), DataTypes.ListValue, false, "a, b"},
+		{"list to text", DataTypes.ParseValue("[a, b]"), DataTypes.StringValue, true, "a, b"},
+		{"same type", DataTypes.NewBool(true), DataTypes.BoolValue, true, "true"},
+		{"any value", DataTypes.NewNumber(1), Semantics.AnyValue, true, "1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value, valid := Semantics.ConvertValue(test.value, test.kind)
+			if valid != test.valid {
+				t.Fatalf("expected valid to be %v", test.valid)
+			}
+			if valid && value.Kind != test.kind && test.kind != Semantics.AnyValue {
+				t.Errorf("got kind %d, want %d", value.Kind, test.kind)
+			}
+			if value.String() != test.printed {
+				t.Errorf("printed as %q, want %q", value.String(), test.printed)
+			}
+		})
+	}
+
+	if value, _ := Semantics.ConvertValue(DataTypes.NewSafeString("<b>"), DataTypes.StringValue); !value.Safe {
+		t.Errorf("converting safe text to text should keep it safe")
+	}
+}
+
+func TestRenderTypedValues(t *testing.T) {
+	runRenderTests(t, []renderTest{
+		{"numbers compare by value", "{% set a = 10 %}{% set b = 9 %}{{ a > b }}\n", "true\n"},
+		{"numbers from text compare by value", "{{ \"10\" > \"9\" }}\n", "true\n"},
+		{"same number written differently", "{{ 1 == 1.0 }}|{{ 2.50 == \"2.5\" }}\n", "true|true\n"},
+		{"text compares without case", "{{ \"Home\" == \"home\" }}\n", "true\n"},
+		{"zero is false", "{% set n = 0 %}{% if n %}yes{% else %}no{% end if %}\n", "no\n"},
+		{"false is false", "{% set b = false %}{% if b %}yes{% else %}no{% end if %}\n", "no\n"},
+		{"text false is a bool", "{% if \"false\" %}yes{% else %}no{% end if %}\n", "yes\n"},
+		{"empty list is false", "{% set l = [] %}{% if l %}yes{% else %}no{% end if %}\n", "no\n"},
+		{"list is printed with commas", "{{ page.tags }}\n", "go, web\n"},
+		{"list item keeps its type", "{% set l = [3, 10] %}{{ l.1 > l.0 }}\n", "true\n"},
+		{"bool prints", "{% set b = TRUE %}{{ b }}\n", "true\n"},
+		{"date prints as written", "{{ page.date_short }}\n", "2017-03-01\n"},
+		{"number prints as written", "{% set n = 1.50 %}{{ n }}|{{ n + 1 }}\n", "1.50|2.5\n"},
+	})
+}
+
+func TestRenderComparisons(t *testing.T) {
+	runRenderTests(t, []renderTest{
+		{"integers of different lengths", "{{ 10 > 9 }}|{{ 9 < 10 }}|{{ 100 >= 99 }}\n", "true|true|true\n"},
+		{"decimals", "{{ 2.5 > 2.25 }}|{{ 0.5 < 0.45 }}\n", "true|false\n"},
+		{"negative numbers", "{{ -2 < -1 }}|{{ -10 > 2 }}\n", "true|false\n"},
+		{"number and text number", "{% set order = \"5\" %}{{ order < 10 }}\n", "true\n"},
+		{"equal numbers", "{{ 3 <= 3 }}|{{ 3 >= 3.0 }}|{{ 3 != 3 }}\n", "true|true|false\n"},
+		{"year", "{% set year = 2017 %}{% if year >= 2017 %}new{% end if %}\n", "new\n"},
+		{"iso dates", "{{ 2017-01-02 > 2016-12-31 }}|{{ 2017-01-02 < 2017-01-10 }}\n", "true|true\n"},
+		{"long dates", "{{ \"January 10, 2017\" > \"February 2, 2016\" }}|{{ \"September 2, 2017\" < \"October 1, 2017\" }}\n", "true|true\n"},
+		{"long and iso date", "{{ \"March 1, 2017\" == page.date_short }}|{{ page.date_short > \"February 28, 2017\" }}\n", "true|true\n"},
+		{"date and time", "{{ \"2017-03-01 10:00\" > page.date_short }}\n", "true\n"},
+		{"text fallback", "{{ \"apple\" < \"banana\" }}|{{ \"b\" > \"A\" }}\n", "true|true\n"},
+		{"number and word", "{{ 10 < \"nine\" }}\n", "true\n"},
+		{"bools", "{{ true > false }}|{{ true == true }}\n", "true|true\n"},
+		{"loop items", "{% foreach site.posts as post where post.date_short < 2017-02-01 %}{{ post.title }}{% end foreach %}\n", "First\n"},
+	})
+}
+
+func TestRenderBooleanPrecedence(t *testing.T) {
+	runRenderTests(t, []renderTest{
+		{"and before or", "{{ true || false && false }}|{{ false && false || true }}\n", "true|true\n"},
+		{"parenthesis first", "{{ (true || false) && false }}\n", "false\n"},
+		{"not before and", "{{ !false && false }}|{{ !(false && false) }}\n", "false|true\n"},
+		{"not before comparison", "{{ !page.title == \"About\" }}\n", "true\n"},
+		{"words", "{{ true and not false }}|{{ false or not true }}\n", "true|false\n"},
+		{"nested parenthesis", "{% if (page.title == \"About\" || (page.author == \"John Doe\" && !page.draft)) %}yes{% end if %}\n", "yes\n"},
+		{"text operators", "{{ \"go\" in page.tags && page.title startswith \"Ho\" }}|{{ page.title endswith \"x\" || page.author contains \"Doe\" }}\n", "true|true\n"},
+		{"not in", "{{ !(\"rust\" in page.tags) }}\n", "true\n"},
+		{"where", "{% foreach site.posts as post where !(post.title == \"Second\") && post.url startswith \"/blog\" %}{{ post.title }}{% end foreach %}\n", "First\n"},
+		{"ternary", "{{ (page.x || page.title) && !page.draft ? \"shown\" : \"hidden\" }}\n", "shown\n"},
+		{"arithmetic before comparison", "{{ 1 + 2 == 3 && 2 * 3 > 5 }}\n", "true\n"},
+	})
+}
@@ -0,0 +1,634 @@
+package Grammar
+
+import (
+	"daphne/DataTypes"
+	"daphne/Errors"
+	"daphne/Helpers"
+	"strings"
+)
+
+/**
+ * Walks the tokens of a template and builds the node tree
+ */
+type templateParser struct {
+	tokens []DataTypes.Token
+	pos    int
+	loops  int              // How many foreach loops the tokens being parsed are in
+	syntax DataTypes.Syntax // The delimiters, for error messages
+}
+
+/**
+ * Name.........: ParseTemplate
+ * Parameters...: source (string) - the template to parse
+ *                syntax (DataTypes.Syntax) - how the template is written
+ * Return.......: []DataTypes.Node - the parsed template
+ *                Errors.Error - any errors
+ * Description..: Tokenizes a template and parses it into a tree of nodes
+ */
+func ParseTemplate(source string, syntax DataTypes.Syntax) ([]DataTypes.Node, Errors.Error) {
+	tokens, err := Tokenize(source, syntax)
+	if err.HasError() {
+		return nil, err
+	}
+
+	parser := templateParser{tokens: tokens, syntax: syntax}
+
+	nodes, end, err := parser.parseNodes()
+	if err.HasError() {
+		return nil, err
+	}
+
+	// Nothing should be left open or closed when the template ends
+	if end != nil {
+		return nil, Errors.NewFatal("Unexpected ", syntax.Tag(end.Value), " on line ", Helpers.ToStr(end.Line))
+	}
+
+	return nodes, Errors.None()
+}
+
+/**
+ * Name.........: parseNodes
+ * Return.......: []DataTypes.Node - the nodes parsed
+ *                *DataTypes.Token - the else, elif, case, default or end tag that stopped parsing, nil at the end of the template
+ *                Errors.Error - any errors
+ * Description..: Parses nodes until the end of the template, or an else, elif, case, default or end tag
+ */
+func (self *templateParser) parseNodes() ([]DataTypes.Node, *DataTypes.Token, Errors.Error) {
+	nodes := []DataTypes.Node{}
+
+	for self.pos < len(self.tokens) {
+		token := self.tokens[self.pos]
+		self.pos++
+
+		switch token.Type {
+		case DataTypes.TextToken:
+			nodes = append(nodes, DataTypes.TextNode{Text: token.Value, Line: token.Line})
+
+		case DataTypes.PrintToken:
+			if IsSuper(token.Value) {
+				nodes = append(nodes, DataTypes.SuperNode{Line: token.Line})
+				continue
+			}
+			nodes = append(nodes, DataTypes.PrintNode{Expression: token.Value, Indent: token.Indent, Context: token.Context, Position: token.Position, Line: token.Line})
+
+		case DataTypes.TagToken:
+			keyword, _ := SplitCommand(token.Value)
+			if keyword == "else" || keyword == "elif" || keyword == "case" || keyword == "default" || keyword == "end" {
+				return nodes, &token, Errors.None()
+			}
+
+			node, err := self.parseCommand(token)
+			if err.HasError() {
+				return nil, nil, err
+			}
+			nodes = append(nodes, node)
+		}
+	}
+
+	return nodes, nil, Errors.None()
+}
+
+/**
+ * Name.........: parseCommand
+ * Parameters...: token (DataTypes.Token) - the tag that starts the command
+ * Return.......: DataTypes.Node - the command
+ *                Errors.Error - any errors
+ * Description..: Parses a {% %} command, and its body if it has one
+ */
+func (self *templateParser) parseCommand(token DataTypes.Token) (DataTypes.Node, Errors.Error) {
+	keyword, rest := SplitCommand(token.Value)
+	line := Helpers.ToStr(token.Line)
+
+	switch keyword {
+	case "if":
+		return self.parseIf(token, rest)
+
+	case "switch":
+		return self.parseSwitch(token, rest)
+
+	case "foreach":
+		node, err := ParseForEach(rest, self.syntax)
+		if err.HasError() {
+			return nil, Errors.NewFatal(err.Msg, " on line ", line)
+		}
+		node.Line = token.Line
+
+		self.loops++
+		body, end, err := self.parseNodes()
+		self.loops--
+
+		node.Body = body
+		if err.HasError() {
+			return nil, err
+		}
+
+		return node, self.expectEnd(end, "foreach", token)
+
+	case "break", "continue":
+		if rest != "" {
+			return nil, Errors.NewFatal("Invalid ", keyword, " on line ", line, ", expected ", self.syntax.Tag(keyword))
+		} else if self.loops == 0 {
+			return nil, Errors.NewFatal(self.syntax.Tag(keyword), " can only be used inside of a foreach loop, found on line ", line)
+		}
+
+		if keyword == "break" {
+			return DataTypes.BreakNode{Line: token.Line}, Errors.None()
+		}
+		return DataTypes.ContinueNode{Line: token.Line}, Errors.None()
+
+	case "set":
+		isSet, variable, value := IsSetCommand(rest)
+		if !isSet {
+			return nil, Errors.NewFatal("Invalid set on line ", line, ", expected ", self.syntax.Tag("set variable = value"))
+		}
+
+		// set global changes the variable for the whole page
+		global := false
+		if words := strings.Fields(variable); len(words) == 2 && Helpers.ToLower(words[0]) == "global" {
+			global, variable = true, words[1]
+		} else if len(words) != 1 {
+			return nil, Errors.NewFatal("Invalid set on line ", line, ", expected ", self.syntax.Tag("set variable = value"), " or ", self.syntax.Tag("set global variable = value"))
+		}
+
+		return DataTypes.SetNode{Variable: variable, Value: value, Global: global, Line: token.Line}, Errors.None()
+
+	case "capture":
+		if rest == "" || len(strings.Fields(rest)) > 1 {
+			return nil, Errors.NewFatal("Invalid capture on line ", line, ", expected ", self.syntax.Tag("capture variable"))
+		}
+
+		body, end, err := self.parseNodes()
+		if err.HasError() {
+			return nil, err
+		}
+
+		return DataTypes.CaptureNode{Variable: rest, Body: body, Line: token.Line}, self.expectEnd(end, "capture", token)
+
+	case "include":
+		if rest == "" {
+			return nil, Errors.NewFatal("Missing file for the include on line ", line)
+		}
+
+		// The file can be in quotes, everything after it are the arguments
+		file := SplitArguments(rest)[0]
+
+		arguments, err := SplitNamedArguments(rest[len(file):])
+		if err.HasError() {
+			return nil, Errors.NewFatal(err.Msg, " for the include on line ", line)
+		}
+
+		return DataTypes.IncludeNode{File: Helpers.StripQuotes(file), Arguments: arguments, Standalone: token.Standalone, Line: token.Line}, Errors.None()
+
+	case "extends":
+		if rest == "" {
+			return nil, Errors.NewFatal("Missing template for the extends on line ", line)
+		}
+
+		return DataTypes.ExtendsNode{Template: Helpers.StripQuotes(rest), Line: token.Line}, Errors.None()
+
+	case "block":
+		if rest == "" || len(Helpers.Split(rest, " ")) > 1 {
+			return nil, Errors.NewFatal("Invalid block on line ", line, ", expected ", self.syntax.Tag("block name"))
+		}
+
+		body, end, err := self.parseNodes()
+		if err.HasError() {
+			return nil, err
+		}
+
+		return DataTypes.BlockNode{Name: rest, Body: body, Line: token.Line}, self.expectEnd(end, "block", token)
+
+	case "macro":
+		node, err := ParseMacro(rest, self.syntax)
+		if err.HasError() {
+			return nil, Errors.NewFatal(err.Msg, " on line ", line)
+		}
+		node.Line = token.Line
+
+		// A macro can be called from anywhere, so a loop around it does not count
+		loops := self.loops
+		self.loops = 0
+		body, end, err := self.parseNodes()
+		self.loops = loops
+
+		node.Body = body
+		if err.HasError() {
+			return nil, err
+		}
+
+		return node, self.expectEnd(end, "macro", token)
+
+	case "import":
+		args := SplitArguments(rest)
+		if len(args) == 2 || len(args) > 3 || (len(args) == 3 && Helpers.ToLower(args[1]) != "as") {
+			return nil, Errors.NewFatal("Invalid import on line ", line, ", expected ", self.syntax.Tag("import file"), " or ", self.syntax.Tag("import file as name"))
+		} else if len(args) == 0 {
+			return nil, Errors.NewFatal("Missing file for the import on line ", line)
+		}
+
+		node := DataTypes.ImportNode{File: Helpers.StripQuotes(args[0]), Line: token.Line}
+		if len(args) == 3 {
+			node.Alias = Helpers.ToLower(args[2])
+		}
+
+		return node, Errors.None()
+	}
+
+	return nil, Errors.NewFatal("Unknown command ", self.syntax.Tag(token.Value), " on line ", line)
+}
+
+/**
+ * Name.........: ParseMacro
+ * Parameters...: signature (string) - everything after the macro keyword
+ *                syntax (DataTypes.Syntax) - the delimiters, for error messages
+ * Return.......: DataTypes.MacroNode - the macro without its body
+ *                Errors.Error - any errors
+ * Description..: Parses the name and parameters of a macro, name(parameter, parameter=default)
+ */
+func ParseMacro(signature string, syntax DataTypes.Syntax) (DataTypes.MacroNode, Errors.Error) {
+	node := DataTypes.MacroNode{}
+
+	isCall, name, params := IsCall(signature)
+	if !isCall || strings.Contains(name, ".") {
+		return node, Errors.NewFatal("Invalid macro, expected ", syntax.Tag("macro name(parameter, parameter=default)"))
+	}
+	node.Name = Helpers.ToLower(name)
+
+	seen := make(map[string]bool)
+	for _, param := range params {
+		parameter := DataTypes.MacroParameter{Name: Helpers.ToLower(param)}
+
+		if isNamed, paramName, value := SplitNamedArgument(param); isNamed {
+			parameter = DataTypes.MacroParameter{Name: paramName, Default: value}
+		} else if !CallNameRegex.MatchString(param) || strings.Contains(param, ".") {
+			return node, Errors.NewFatal("Invalid parameter ", param, " for the macro ", name)
+		} else if len(node.Parameters) > 0 && node.Parameters[len(node.Parameters)-1].Default != "" {
+			return node, Errors.NewFatal("The parameter ", param, " of the macro ", name, " needs a default, it comes after one that has a default")
+		}
+
+		if seen[parameter.Name] {
+			return node, Errors.NewFatal("The parameter ", parameter.Name, " is in the macro ", name, " twice")
+		}
+		seen[parameter.Name] = true
+
+		node.Parameters = append(node.Parameters, parameter)
+	}
+
+	return node, Errors.None()
+}
+
+/**
+ * Name.........: parseIf
+ * Parameters...: token (DataTypes.Token) - the tag that starts the if statement
+ *                condition (string) - the condition of the if
+ * Return.......: DataTypes.Node - the if statement
+ *                Errors.Error - any errors
+ * Description..: Parses an if statement with any number of else if branches and an optional else
+ */
+func (self *templateParser) parseIf(token DataTypes.Token, condition string) (DataTypes.Node, Errors.Error) {
+	node := DataTypes.IfNode{Line: token.Line}
+	branch := token
+
+	for {
+		if condition == "" {
+			return nil, Errors.NewFatal("Missing condition for the if on line ", Helpers.ToStr(branch.Line))
+		}
+
+		body, end, err := self.parseNodes()
+		if err.HasError() {
+			return nil, err
+		}
+		node.Branches = append(node.Branches, DataTypes.IfBranch{Condition: condition, Body: body, Line: branch.Line})
+
+		if end == nil {
+			return nil, self.expectEnd(end, "if", token)
+		}
+
+		// {% else if condition %} and {% elif condition %} start another branch
+		keyword, rest := SplitCommand(end.Value)
+		if keyword == "elif" {
+			condition = rest
+		} else if keyword == "else" && rest != "" {
+			elseKeyword, elseCondition := SplitCommand(rest)
+			if elseKeyword != "if" {
+				return nil, Errors.NewFatal("Invalid ", self.syntax.Tag(end.Value), " on line ", Helpers.ToStr(end.Line), ", expected ", self.syntax.Tag("else"), " or ", self.syntax.Tag("else if condition"))
+			}
+			condition = elseCondition
+		} else if keyword == "else" {
+			// Only the end can follow the else branch
+			node.Else, end, err = self.parseNodes()
+			if err.HasError() {
+				return nil, err
+			}
+
+			return node, self.expectEnd(end, "if", token)
+		} else {
+			return node, self.expectEnd(end, "if", token)
+		}
+
+		branch = *end
+	}
+}
+
+/**
+ * Name.........: parseSwitch
+ * Parameters...: token (DataTypes.Token) - the tag that starts the switch
+ *                value (string) - the value of the switch
+ * Return.......: DataTypes.Node - the switch
+ *                Errors.Error - any errors
+ * Description..: Parses a switch with any number of cases and an optional default at the end
+ */
+func (self *templateParser) parseSwitch(token DataTypes.Token, value string) (DataTypes.Node, Errors.Error) {
+	node := DataTypes.SwitchNode{Value: value, Line: token.Line}
+	if value == "" {
+		return nil, Errors.NewFatal("Missing value for the switch on line ", Helpers.ToStr(token.Line))
+	}
+
+	// Nothing but whitespace can come before the first case
+	body, end, err := self.parseNodes()
+	if err.HasError() {
+		return nil, err
+	}
+
+	for _, child := range body {
+		if text, isText := child.(DataTypes.TextNode); !isText || Helpers.Trim(text.Text) != "" {
+			return nil, Errors.NewFatal("Only ", self.syntax.Tag("case"), " and ", self.syntax.Tag("default"), " can be inside of the switch on line ", Helpers.ToStr(token.Line), ", found something else on line ", Helpers.ToStr(child.StartLine()))
+		}
+	}
+
+	for end != nil {
+		keyword, rest := SplitCommand(end.Value)
+		branch := *end
+
+		if keyword == "case" {
+			if rest == "" {
+				return nil, Errors.NewFatal("Missing value for the case on line ", Helpers.ToStr(branch.Line))
+			}
+
+			values := DataTypes.SplitList(rest)
+			for _, caseValue := range values {
+				if caseValue == "" {
+					return nil, Errors.NewFatal("Missing value for the case on line ", Helpers.ToStr(branch.Line))
+				}
+			}
+
+			body, end, err = self.parseNodes()
+			if err.HasError() {
+				return nil, err
+			}
+			node.Cases = append(node.Cases, DataTypes.SwitchCase{Values: values, Body: body, Line: branch.Line})
+		} else if keyword == "default" && rest == "" {
+			// Only the end can follow the default
+			node.Default, end, err = self.parseNodes()
+			if err.HasError() {
+				return nil, err
+			}
+
+			return node, self.expectEnd(end, "switch", token)
+		} else {
+			break
+		}
+	}
+
+	return node, self.expectEnd(end, "switch", token)
+}
+
+/**
+ * Name.........: expectEnd
+ * Parameters...: end (*DataTypes.Token) - the tag that stopped parsing the body
+ *                control (string) - the command that should be ended
+ *                start (DataTypes.Token) - the tag that started the command
+ * Return.......: Errors.Error
+ * Description..: Makes sure a command was closed by the right end tag
+ */
+func (self *templateParser) expectEnd(end *DataTypes.Token, control string, start DataTypes.Token) Errors.Error {
+	if end == nil {
+		return Errors.NewFatal("No ", self.syntax.Tag("end "+control), " for the ", control, " on line ", Helpers.ToStr(start.Line))
+	}
+
+	keyword, rest := SplitCommand(end.Value)
+	if keyword != "end" || rest != control {
+		return Errors.NewFatal("Expected ", self.syntax.Tag("end "+control), " for the ", control, " on line ", Helpers.ToStr(start.Line), " but found ", self.syntax.Tag(end.Value), " on line ", Helpers.ToStr(end.Line))
+	}
+
+	return Errors.None()
+}
+
+/**
+ * Name.........: SplitCommand
+ * Parameters...: command (string) - the inside of a {% %} tag
+ * Return.......: string - the keyword
+ *                string - everything after the keyword
+ * Description..: Splits a command into its keyword and its arguments
+ */
+func SplitCommand(command string) (string, string) {
+	command = Helpers.Trim(command)
+
+	for i, c := range command {
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' {
+			return command[:i], Helpers.Trim(command[i:])
+		}
+	}
+
+	return command, ""
+}
+
+// Clauses that can follow the alias of a foreach loop
+var foreachClauses = []string{"where", "order by", "limit", "offset"}
+
+/**
+ * Name.........: ParseForEach
+ * Parameters...: condition (string) - everything after the foreach keyword
+ *                syntax (DataTypes.Syntax) - the delimiters, for error messages
+ * Return.......: DataTypes.ForeachNode - the loop without its body
+ *                Errors.Error - any errors
+ * Description..: Parses the collection, alias and the where, order by, limit and offset clauses of a foreach loop
+ */
+func ParseForEach(condition string, syntax DataTypes.Syntax) (DataTypes.ForeachNode, Errors.Error) {
+	node := DataTypes.ForeachNode{}
+
+	loop, clauses, err := SplitClauses(condition, foreachClauses)
+	if err.HasError() {
+		return node, err
+	}
+
+	node.Collection, node.Alias = ParseForEachCondition(loop)
+	if node.Collection == "" || node.Alias == "" {
+		return node, Errors.NewFatal("Invalid foreach, expected ", syntax.Tag("foreach collection as alias"))
+	}
+
+	// as key, value names both parts of every entry of a map
+	aliases := DataTypes.SplitList(node.Alias)
+	for _, alias := range aliases {
+		if len(aliases) > 2 || !CallNameRegex.MatchString(alias) || strings.Contains(alias, ".") {
+			return node, Errors.NewFatal("Invalid foreach, expected ", syntax.Tag("foreach collection as alias"), " or ", syntax.Tag("foreach map as key, value"))
+		}
+	}
+
+	if len(aliases) == 2 {
+		node.Key, node.Alias = Helpers.ToLower(aliases[0]), aliases[1]
+	}
+	node.Alias = Helpers.ToLower(node.Alias)
+
+	node.Where = clauses["where"]
+	node.Limit = clauses["limit"]
+	node.Offset = clauses["offset"]
+
+	// Sort direction is the last word of the order by
+	node.OrderBy = clauses["order by"]
+	keyword, direction := SplitLastWord(node.OrderBy)
+	if direction == "asc" || direction == "desc" {
+		node.OrderBy = keyword
+		node.Descending = direction == "desc"
+	}
+
+	for _, name := range foreachClauses {
+		if value, exists := clauses[name]; exists && value == "" {
+			return node, Errors.NewFatal("Missing value for the ", name, " of the foreach")
+		}
+	}
+
+	return node, Errors.None()
+}
+
+/**
+ * Name.........: SplitClauses
+ * Parameters...: command (string) - the command to split
+ *                keywords ([]string) - the keywords that start a clause
+ * Return.......: string - everything before the first clause
+ *                map[string]string - the value of every clause found, by keyword
+ *                Errors.Error - any errors
+ * Description..: Splits a command at keywords that are not inside of quotes or parenthesis
+ */
+func SplitClauses(command string, keywords []string) (string, map[string]string, Errors.Error) {
+	clauses := make(map[string]string)
+
+	head := ""
+	current := ""
+	start := 0
+
+	quote := byte(0)
+	depth := 0
+
+	for i := 0; i < len(command); i++ {
+		c := command[i]
+
+		if quote != 0 {
+			if c == quote && command[i-1] != '\\' {
+				quote = 0
+			}
+			continue
+		}
+
+		switch c {
+		case '"', '\'':
+			quote = c
+			continue
+		case '(', '[':
+			depth++
+			continue
+		case ')', ']':
+			depth--
+			continue
+		}
+
+		// Keywords have to be whole words outside of anything else
+		if depth > 0 || (i > 0 && command[i-1] != ' ') {
+			continue
+		}
+
+		for _, keyword := range keywords {
+			end := i + len(keyword)
+			if Helpers.ToLower(Helpers.Substring(command, i, end-1)) != keyword || (end < len(command) && command[end] != ' ') {
+				continue
+			}
+
+			if current == "" {
+				head = command[start:i]
+			} else {
+				clauses[current] = Helpers.Trim(command[start:i])
+			}
+
+			if _, exists := clauses[keyword]; exists || keyword == current {
+				return "", nil, Errors.NewFatal("The ", keyword, " clause can only be used once")
+			}
+
+			current = keyword
+			start = end
+			i = end - 1
+			break
+		}
+	}
+
+	if current == "" {
+		head = command
+	} else {
+		clauses[current] = Helpers.Trim(command[start:])
+	}
+
+	return Helpers.Trim(head), clauses, Errors.None()
+}
+
+/**
+ * Name.........: SplitLastWord
+ * Parameters...: inp (string) - the string to split
+ * Return.......: string - everything before the last word
+ *                string - the last word, in lower case
+ * Description..: Splits the last word off of a string
+ */
+func SplitLastWord(inp string) (string, string) {
+	inp = Helpers.Trim(inp)
+
+	for i := len(inp) - 1; i >= 0; i-- {
+		if inp[i] == ' ' {
+			return Helpers.Trim(inp[:i]), Helpers.ToLower(inp[i+1:])
+		}
+	}
+
+	return inp, ""
+}
+
+/**
+ * Name.........: ParseForEachCondition
+ * Parameters...: condition (string) - everything after the foreach keyword
+ * Return.......: string - the collection to loop through
+ *                string - the alias for each item, or the aliases of the key and the value
+ * Description..: Splits a foreach condition into the collection and the alias
+ */
+func ParseForEachCondition(condition string) (string, string) {
+	operands := Helpers.Split(condition, " as ")
+
+	if len(operands) < 2 {
+		return "", ""
+	}
+
+	return Helpers.Trim(operands[0]), Helpers.Trim(operands[1])
+}
+
+/**
+ * Name.........: IsSetCommand
+ * Parameters...: command (string) - everything after the set keyword
+ * Return.......: bool - If it is a valid set command
+ *                string - variable to set
+ *                string - value to set
+ * Description..: Splits a set command into the variable and the value
+ */
+func IsSetCommand(command string) (bool, string, string) {
+	for i := 0; i < len(command); i++ {
+		if command[i] != '=' {
+			continue
+		}
+
+		// Make sure it is not a comparison
+		if i+1 < len(command) && command[i+1] == '=' {
+			return false, "", ""
+		}
+
+		variable := Helpers.Trim(command[:i])
+		value := Helpers.Trim(command[i+1:])
+
+		return variable != "" && value != "", variable, value
+	}
+
+	return false, "", ""
+}
@@ -0,0 +1,213 @@
+package Grammar
+
+import (
+	"daphne/DataTypes"
+	"testing"
+)
+
+// The default delimiters
+var testSyntax = DataTypes.Syntax{TagOpening: "{%", TagClosing: "%}", PrintOpening: "{{", PrintClosing: "}}", CommentOpening: "{#", CommentClosing: "#}"}
+
+/**
+ * Name.........: parseIfNode
+ * Parameters...: t (*testing.T) - the test
+ *                template (string) - a template that is a single if statement
+ * Return.......: DataTypes.IfNode
+ * Description..: Parses a template and makes sure it is nothing but an if statement
+ */
+func parseIfNode(t *testing.T, template string) DataTypes.IfNode {
+	t.Helper()
+
+	nodes, err := ParseTemplate(template, testSyntax)
+	if err.HasError() {
+		t.Fatalf("parsing %q: %s", template, err.Msg)
+	}
+	if len(nodes) != 1 {
+		t.Fatalf("parsing %q: expected 1 node, got %d", template, len(nodes))
+	}
+
+	node, isIf := nodes[0].(DataTypes.IfNode)
+	if !isIf {
+		t.Fatalf("parsing %q: expected an if statement, got %T", template, nodes[0])
+	}
+
+	return node
+}
+
+func TestParseIfBranches(t *testing.T) {
+	tests := []struct {
+		name       string
+		template   string
+		conditions []string
+		hasElse    bool
+	}{
+		{"if", "{% if a %}\nA\n{% end if %}\n", []string{"a"}, false},
+		{"else", "{% if a %}\nA\n{% else %}\nB\n{% end if %}\n", []string{"a"}, true},
+		{"else if", "{% if a %}\nA\n{% else if b %}\nB\n{% end if %}\n", []string{"a", "b"}, false},
+		{"elif", "{% if a %}\nA\n{% elif b %}\nB\n{% end if %}\n", []string{"a", "b"}, false},
+		{"else if and else", "{% if a %}\nA\n{% else if b %}\nB\n{% else %}\nC\n{% end if %}\n", []string{"a", "b"}, true},
+		{"mixed chain", "{% if a %}\nA\n{% elif b %}\nB\n{% else if c == \"x\" %}\nC\n{% elif d %}\nD\n{% else %}\nE\n{% end if %}\n", []string{"a", "b", "c == \"x\"", "d"}, true},
+		{"inline", "{% if a %}A{% elif b %}B{% else %}C{% end if %}", []string{"a", "b"}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			node := parseIfNode(t, test.template)
+
+			if len(node.Branches) != len(test.conditions) {
+				t.Fatalf("expected %d branches, got %d", len(test.conditions), len(node.Branches))
+			}
+			for i, branch := range node.Branches {
+				if branch.Condition != test.conditions[i] {
+					t.Errorf("branch %d: expected the condition %q, got %q", i, test.conditions[i], branch.Condition)
+				}
+				if len(branch.Body) != 1 {
+					t.Errorf("branch %d: expected 1 node in the body, got %d", i, len(branch.Body))
+				}
+			}
+			if (len(node.Else) > 0) != test.hasElse {
+				t.Errorf("expected an else branch: %v, got %d nodes", test.hasElse, len(node.Else))
+			}
+		})
+	}
+}
+
+func TestParseIfLines(t *testing.T) {
+	node := parseIfNode(t, "{% if a %}\nA\n{% elif b %}\nB\n{% else if c %}\nC\n{% end if %}\n")
+
+	for i, line := range []int{1, 3, 5} {
+		if node.Branches[i].Line != line {
+			t.Errorf("branch %d: expected line %d, got %d", i, line, node.Branches[i].Line)
+		}
+	}
+}
+
+func TestParseNestedIf(t *testing.T) {
+	template := "{% if a %}\n" +
+		"{% if b %}\nAB\n{% elif c %}\nAC\n{% else %}\nA\n{% end if %}\n" +
+		"{% elif d %}\n" +
+		"{% foreach site.posts as post %}\n{% if post.e %}\nE\n{% else %}\nF\n{% end if %}\n{% end foreach %}\n" +
+		"{% else %}\n" +
+		"{% if g %}\nG\n{% end if %}\n" +
+		"{% end if %}\n"
+
+	node := parseIfNode(t, template)
+	if len(node.Branches) != 2 || len(node.Else) != 1 {
+		t.Fatalf("expected 2 branches and an else, got %d branches and %d else nodes", len(node.Branches), len(node.Else))
+	}
+
+	inner, isIf := node.Branches[0].Body[0].(DataTypes.IfNode)
+	if !isIf || len(inner.Branches) != 2 || len(inner.Else) != 1 {
+		t.Errorf("expected an if with an elif and an else inside of the if, got %#v", node.Branches[0].Body[0])
+	}
+
+	loop, isLoop := node.Branches[1].Body[0].(DataTypes.ForeachNode)
+	if !isLoop || len(loop.Body) != 1 {
+		t.Fatalf("expected a foreach inside of the elif, got %#v", node.Branches[1].Body[0])
+	}
+	if inLoop, isIf := loop.Body[0].(DataTypes.IfNode); !isIf || len(inLoop.Branches) != 1 || len(inLoop.Else) != 1 {
+		t.Errorf("expected an if with an else inside of the foreach, got %#v", loop.Body[0])
+	}
+
+	if inElse, isIf := node.Else[0].(DataTypes.IfNode); !isIf || inElse.Branches[0].Condition != "g" {
+		t.Errorf("expected an if inside of the else, got %#v", node.Else[0])
+	}
+}
+
+func TestParseIfErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+	}{
+		{"missing condition", "{% if %}\nA\n{% end if %}\n"},
+		{"missing elif condition", "{% if a %}\nA\n{% elif %}\nB\n{% end if %}\n"},
+		{"missing else if condition", "{% if a %}\nA\n{% else if %}\nB\n{% end if %}\n"},
+		{"else with something else", "{% if a %}\nA\n{% else when b %}\nB\n{% end if %}\n"},
+		{"elif after else", "{% if a %}\nA\n{% else %}\nB\n{% elif c %}\nC\n{% end if %}\n"},
+		{"two elses", "{% if a %}\nA\n{% else %}\nB\n{% else %}\nC\n{% end if %}\n"},
+		{"no end", "{% if a %}\nA\n{% elif b %}\nB\n"},
+		{"elif without if", "{% elif a %}\nA\n"},
+		{"else without if", "A\n{% else %}\nB\n"},
+		{"nested without end", "{% if a %}\n{% if b %}\nB\n{% end if %}\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := ParseTemplate(test.template, testSyntax); !err.HasError() {
+				t.Errorf("expected %q to fail", test.template)
+			}
+		})
+	}
+}
+
+func TestParseErrorsUseSyntax(t *testing.T) {
+	syntax := DataTypes.Syntax{TagOpening: "<%", TagClosing: "%>", PrintOpening: "<%=", PrintClosing: "%>", CommentOpening: "<%#", CommentClosing: "%>"}
+
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{"no end", "<% if a %>\nA\n", "No <% end if %> for the if on line 1"},
+		{"wrong end", "<% if a %>\nA\n<% end foreach %>\n", "Expected <% end if %> for the if on line 1 but found <% end foreach %> on line 3"},
+		{"unexpected end", "A\n<% end if %>\n", "Unexpected <% end if %> on line 2"},
+		{"unknown command", "<% frobnicate %>\n", "Unknown command <% frobnicate %> on line 1"},
+		{"break outside of a loop", "<% break %>\n", "<% break %> can only be used inside of a foreach loop, found on line 1"},
+		{"invalid set", "<% set a %>\n", "Invalid set on line 1, expected <% set variable = value %>"},
+		{"invalid foreach", "<% foreach site.posts %>\n<% end foreach %>\n", "Invalid foreach, expected <% foreach collection as alias %> on line 1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseTemplate(test.template, syntax)
+			if !err.HasError() {
+				t.Fatalf("expected %q to fail", test.template)
+			}
+			if err.Msg != test.expected {
+				t.Errorf("expected the error %q, got %q", test.expected, err.Msg)
+			}
+		})
+	}
+}
+
+func TestParseSwitchCases(t *testing.T) {
+	nodes, err := ParseTemplate("{% switch a %}\n{% case 1, \"x, y\" %}\nA\n{% case 2 %}\nB\n{% default %}\nC\n{% end switch %}\n", testSyntax)
+	if err.HasError() {
+		t.Fatalf("parsing the switch: %s", err.Msg)
+	}
+
+	node, isSwitch := nodes[0].(DataTypes.SwitchNode)
+	if !isSwitch || len(node.Cases) != 2 {
+		t.Fatalf("expected a switch with 2 cases, got %#v", nodes[0])
+	}
+
+	values := node.Cases[0].Values
+	if len(values) != 2 || values[0] != "1" || values[1] != "\"x, y\"" {
+		t.Errorf("expected the values 1 and \"x, y\", got %q", values)
+	}
+	if len(node.Cases[1].Values) != 1 || node.Cases[1].Values[0] != "2" {
+		t.Errorf("expected the value 2, got %q", node.Cases[1].Values)
+	}
+}
+
+func TestParseSwitchErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+	}{
+		{"missing value", "{% switch %}\n{% end switch %}\n"},
+		{"missing case value", "{% switch a %}\n{% case %}\nA\n{% end switch %}\n"},
+		{"empty case value", "{% switch a %}\n{% case 1, %}\nA\n{% end switch %}\n"},
+		{"empty first case value", "{% switch a %}\n{% case , 1 %}\nA\n{% end switch %}\n"},
+		{"text before the first case", "{% switch a %}\nA\n{% case 1 %}\nB\n{% end switch %}\n"},
+		{"case after the default", "{% switch a %}\n{% default %}\nA\n{% case 1 %}\nB\n{% end switch %}\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := ParseTemplate(test.template, testSyntax); !err.HasError() {
+				t.Errorf("expected %q to fail", test.template)
+			}
+		})
+	}
+}
@@ -64,6 +64,22 @@ func ToString(i int) (string) {
     return ToStr(i)
 }
 
+/**
+ * Converts a string to an int, the bool is false if it is not a whole number
+ */
+func ToInt(str string) (int, bool) {
+    i, err := strconv.Atoi(Trim(str))
+    return i, err == nil
+}
+
+/**
+ * Converts a string to a float, the bool is false if it is not a number
+ */
+func ToFloat(str string) (float64, bool) {
+    f, err := strconv.ParseFloat(Trim(str), 64)
+    return f, err == nil
+}
+
 
 /**
  * Removes trailing spaces
@@ -73,6 +89,14 @@ func Trim(str string) (string) {
 }
 
 
+/**
+ * Removes a suffix from a string if it has it
+ */
+func TrimSuffix(str string, suffix string) (string) {
+    return strings.TrimSuffix(str, suffix)
+}
+
+
 /**
  * To Lowercase
  */
@@ -1,6 +1,7 @@
 package Parser
 
 import (
+	"daphne/DataTypes"
 	"daphne/Errors"
 	"daphne/FileSystem"
 	"daphne/Grammar"
@@ -18,7 +19,7 @@ import (
 func ParseConfigFile(wd string, ProgramState *State.CompilerState) Errors.Error {
 	file := wd + "\\_config.daphne"
 
-	config := make(map[string]string)
+	config := make(map[string]DataTypes.Value)
 
 	// Read the file
 	contents, err := FileSystem.ReadFile(file)
@@ -35,9 +36,7 @@ func ParseConfigFile(wd string, ProgramState *State.CompilerState) Errors.Error
 	// Apply defaults
 	ApplyDefaultConfigOptions(config)
 
-	if config["compiler.ignore"] != "" {
-		ProgramState.Ignore = append(ProgramState.Ignore, Helpers.Split(config["compiler.ignore"], ",")...)
-	}
+	ProgramState.Ignore = append(ProgramState.Ignore, ConfigList(config["compiler.ignore"])...)
 
 	// Add to the compiler state
 	ProgramState.Config = config
@@ -46,46 +45,50 @@ func ParseConfigFile(wd string, ProgramState *State.CompilerState) Errors.Error
 
 /**
  * Name.........: ApplyDefaultConfigOptions
- * Parameters...: config (map[string]string) - the config to apply defualts to
+ * Parameters...: config (map[string]DataTypes.Value) - the config to apply defualts to
  * Description..: Adds default options to a configuration if they do not exist
  */
-func ApplyDefaultConfigOptions(config map[string]string) {
+func ApplyDefaultConfigOptions(config map[string]DataTypes.Value) {
 	defaults := map[string]string{
-		"compiler.source":             ".",
-		"compiler.output":             "_build",
-		"site.template":               "default",
-		"compiler.template_dir":       "_templates",
-		"compiler.include_dir":        "_includes",
-		"compiler.posts_dir":          "_posts",
-		"compiler.posts_asset_dir":    "_posts\\assets",
-		"compiler.drafts_dir":         "_posts\\_drafts",
-		"compiler.tags.meta":          "---",
-		"compiler.tags.opening":       "{%",
-		"compiler.tags.closing":       "%}",
-		"compiler.tags.print.opening": "{{",
-		"compiler.tags.print.closing": "}}",
-		"blog.permalink":              "/blog/%slug%",
-		"blog.foldericize":            "true",
-		"blog.excerpt":                "<!-- more -->",
-		"permalinks.blog":             "/blog/%slug%",
+		"compiler.source":               ".",
+		"compiler.output":               "_build",
+		"site.template":                 "default",
+		"compiler.template_dir":         "_templates",
+		"compiler.include_dir":          "_includes",
+		"compiler.data_dir":             "_data",
+		"compiler.posts_dir":            "_posts",
+		"compiler.posts_asset_dir":      "_posts\\assets",
+		"compiler.drafts_dir":           "_posts\\_drafts",
+		"compiler.tags.meta":            "---",
+		"compiler.tags.opening":         "{%",
+		"compiler.tags.closing":         "%}",
+		"compiler.tags.print.opening":   "{{",
+		"compiler.tags.print.closing":   "}}",
+		"compiler.tags.comment.opening": "{#",
+		"compiler.tags.comment.closing": "#}",
+		"blog.permalink":                "/blog/%slug%",
+		"blog.foldericize":              "true",
+		"blog.excerpt":                  "<!-- more -->",
+		"permalinks.blog":               "/blog/%slug%",
 	}
 
 	for key, val := range defaults {
-		if config[key] == "" {
-			config[key] = val
+		if config[key].String() == "" {
+			config[key] = DataTypes.NewString(val)
 		}
 	}
 
 	// Add folders to ingore
-	toIgnore := []string{config["compiler.include_dir"], config["compiler.template_dir"], config["compiler.output"], config["compiler.posts_asset_dir"]}
-	if config["compiler.ignore"] != "" {
-		config["compiler.ignore"] = config["compiler.ignore"] + ","
+	toIgnore := ConfigList(config["compiler.ignore"])
+	for _, key := range []string{"compiler.include_dir", "compiler.data_dir", "compiler.template_dir", "compiler.output", "compiler.posts_asset_dir"} {
+		toIgnore = append(toIgnore, config[key].String())
 	}
-	config["compiler.ignore"] = config["compiler.ignore"] + Helpers.Join(toIgnore, ",")
+	config["compiler.ignore"] = DataTypes.NewString(Helpers.Join(toIgnore, ","))
 
-	if config["site.url"] != "" {
-		if config["site.url"][len(config["site.url"])-1:] != "/" {
-			config["site.url"] = config["site.url"] + "/"
+	url := config["site.url"].String()
+	if url != "" {
+		if url[len(url)-1:] != "/" {
+			config["site.url"] = DataTypes.NewString(url + "/")
 		}
 	}
 
@@ -95,9 +98,10 @@ func ApplyDefaultConfigOptions(config map[string]string) {
 	    }
 	}*/
 
-	if config["permalinks.blog"] != "" {
-		if config["permalinks.blog"][:1] == "/" {
-			config["permalinks.blog"] = config["permalinks.blog"][1:]
+	permalink := config["permalinks.blog"].String()
+	if permalink != "" {
+		if permalink[:1] == "/" {
+			config["permalinks.blog"] = DataTypes.NewString(permalink[1:])
 		}
 	}
 
@@ -106,12 +110,12 @@ func ApplyDefaultConfigOptions(config map[string]string) {
 /**
  * Name.........: ParseConfig
  * Parameters...: contents ([]string) - contents of a file to parse
- * Return.......: map[string]map[string]string - contents of the config file
+ * Return.......: map[string]DataTypes.Value - contents of the config file
  *                error - any errors
  * Description..: Parses an already read config file
  */
-func ParseConfig(contents []string) (map[string]string, Errors.Error) {
-	config := make(map[string]string)
+func ParseConfig(contents []string) (map[string]DataTypes.Value, Errors.Error) {
+	config := make(map[string]DataTypes.Value)
 
 	lineNum := "0"
 	currentSection := ""
@@ -164,7 +168,7 @@ func ParseConfig(contents []string) (map[string]string, Errors.Error) {
 			}
 
 			// Put into configuration
-			config[currentSection+"."+matches[1]] = matches[2]
+			config[currentSection+"."+matches[1]] = DataTypes.ParseValue(matches[2])
 
 		// Section End
 		case Grammar.ConfigRegex.SectionEnd.MatchString(line):
@@ -190,3 +194,28 @@ func ParseConfig(contents []string) (map[string]string, Errors.Error) {
 
 	return config, Errors.None()
 }
+
+/**
+ * Name.........: ConfigList
+ * Parameters...: value (DataTypes.Value) - a list, or comma separated text
+ * Return.......: []string - every item of the list
+ * Description..: Reads a config value that holds a list of names
+ */
+func ConfigList(value DataTypes.Value) []string {
+	items := []string{}
+
+	if value.Kind == DataTypes.ListValue {
+		for _, item := range value.List {
+			items = append(items, Helpers.Trim(item.String()))
+		}
+		return items
+	}
+
+	for _, item := range Helpers.Split(value.String(), ",") {
+		if Helpers.Trim(item) != "" {
+			items = append(items, Helpers.Trim(item))
+		}
+	}
+
+	return items
+}
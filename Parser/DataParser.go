@@ -0,0 +1,62 @@
+package Parser
+
+import (
+	"daphne/DataTypes"
+	"daphne/Errors"
+	"daphne/FileSystem"
+	"daphne/Helpers"
+	"daphne/State"
+	"encoding/json"
+	"path/filepath"
+)
+
+/**
+ * Name.........: ParseDataFiles
+ * Parameters...: ProgramState (*State.CompilerState) - The State
+ * Return.......: Errors.Error - any errors
+ * Description..: Reads every file in the data directory into site.data, _data\team.json becomes site.data.team
+ *                and _data\links\social.daphne becomes site.data.links.social. .json files can hold any JSON,
+ *                .daphne files are written like the config, and other files are skipped
+ */
+func ParseDataFiles(ProgramState *State.CompilerState) Errors.Error {
+	dir := ProgramState.Setting("compiler.data_dir")
+
+	for _, file := range FileSystem.CollapseDirectory(ProgramState.Path(dir), "", true) {
+		ext := Helpers.ToLower(filepath.Ext(file.Info.Name()))
+		if ext != ".json" && ext != ".daphne" {
+			continue
+		}
+
+		// Folders inside of the data directory are sections of site.data
+		name := file.Info.Name()[:len(file.Info.Name())-len(ext)]
+		if file.Directory != "" {
+			name = Helpers.Join(Helpers.Split(file.Directory, "\\"), ".") + "." + name
+		}
+		name = "site.data." + Helpers.ToLower(name)
+
+		path := ProgramState.Path(dir + "\\" + file.Directory + "\\" + file.Info.Name())
+		contents, err := FileSystem.ReadFile(path)
+		if err.HasError() {
+			return err
+		}
+
+		if ext == ".json" {
+			value := DataTypes.Value{}
+			if err := json.Unmarshal([]byte(Helpers.Join(contents, "\n")), &value); err != nil {
+				return Errors.NewFatal(path, ": ", err.Error())
+			}
+			ProgramState.Config[name] = value
+			continue
+		}
+
+		data, err := ParseConfig(contents)
+		if err.HasError() {
+			return Errors.NewFatal(path, ": ", err.Msg)
+		}
+		for key, value := range data {
+			ProgramState.Config[name+"."+key] = value
+		}
+	}
+
+	return Errors.None()
+}
@@ -26,7 +26,7 @@ func ParsePage(file string, ProgramState *State.CompilerState) (DataTypes.Page,
 	}
 
 	// Create struct to hold the page information
-	page := DataTypes.Page{Meta: make(map[string]string), Content: []string{}, File: file}
+	page := DataTypes.Page{Meta: make(map[string]DataTypes.Value), Content: []string{}, File: file}
 
 	state := 0
 
@@ -39,7 +39,7 @@ func ParsePage(file string, ProgramState *State.CompilerState) (DataTypes.Page,
 		switch state {
 		// 0 - looking for config opener
 		case 0:
-			if line != ProgramState.Config["compiler.tags.meta"] {
+			if line != ProgramState.Setting("compiler.tags.meta") {
 				return page, Errors.NewFatal("First line of ", file, " can ONLY Be the opening meta tags")
 			}
 
@@ -47,7 +47,7 @@ func ParsePage(file string, ProgramState *State.CompilerState) (DataTypes.Page,
 
 		// 1 - Waiting for end meta config
 		case 1:
-			if line == ProgramState.Config["compiler.tags.meta"] {
+			if line == ProgramState.Setting("compiler.tags.meta") {
 				meta := Helpers.Copy(contents[:i])
 				meta[0] = "page: {"
 				meta = append(meta, "}")
@@ -61,11 +61,11 @@ func ParsePage(file string, ProgramState *State.CompilerState) (DataTypes.Page,
 					return page, err
 				}
 
-				if page.Meta["page.slug"] == "" {
-					page.Meta["page.slug"] = page.GetSlug()
+				if page.Meta["page.slug"].String() == "" {
+					page.Meta["page.slug"] = DataTypes.NewString(page.GetSlug())
 				}
-				page.Meta["page.file"] = file
-				page.Meta["page.url"] = ProgramState.GetPageURL(page)
+				page.Meta["page.file"] = DataTypes.NewString(file)
+				page.Meta["page.url"] = DataTypes.NewString(ProgramState.GetPageURL(page))
 
 				ApplyDefaultMetaConfig(page.Meta, ProgramState)
 
@@ -76,7 +76,7 @@ func ParsePage(file string, ProgramState *State.CompilerState) (DataTypes.Page,
 		case 2:
 			if line != "" {
 				page.Content = Helpers.Copy(contents[i:])
-				page.Meta["content"] = Helpers.Join(page.Content, "\n")
+				page.Meta["content"] = DataTypes.NewSafeString(Helpers.Join(page.Content, "\n"))
 
 				page.OutFile = ProgramState.GetPageOutpath(page)
 
@@ -88,7 +88,7 @@ func ParsePage(file string, ProgramState *State.CompilerState) (DataTypes.Page,
 
 	// If the state is 2, then the file is blank, let this be okay
 	if state == 2 {
-		page.Meta["page.slug"] = page.GetSlug()
+		page.Meta["page.slug"] = DataTypes.NewString(page.GetSlug())
 		page.OutFile = ProgramState.GetPageOutpath(page)
 		return page, Errors.NewWarning(file, " is empty\n")
 	}
@@ -126,15 +126,15 @@ func ParsePost(file string, ProgramState *State.CompilerState) (DataTypes.Page,
 		return page, Errors.NewFatal(err1.Error())
 	}
 
-	page.Meta["page.date"] = t.Format("January 2, 2006")
-	page.Meta["page.date_year"] = t.Format("2006")
-	page.Meta["page.date_month"] = t.Format("01")
-	page.Meta["page.date_day"] = t.Format("02")
-	page.Meta["page.date_short"] = t.Format("2006-01-02")
+	page.Meta["page.date"] = DataTypes.NewDate(t, t.Format("January 2, 2006"))
+	page.Meta["page.date_year"] = DataTypes.ParseValue(t.Format("2006"))
+	page.Meta["page.date_month"] = DataTypes.ParseValue(t.Format("01"))
+	page.Meta["page.date_day"] = DataTypes.ParseValue(t.Format("02"))
+	page.Meta["page.date_short"] = DataTypes.NewDate(t, t.Format("2006-01-02"))
 
 	// Get the post URL Information
-	page.Meta["page.slug"] = page.GetSlug()
-	page.Meta["page.url"] = ProgramState.GetPostURL(page)
+	page.Meta["page.slug"] = DataTypes.NewString(page.GetSlug())
+	page.Meta["page.url"] = DataTypes.NewString(ProgramState.GetPostURL(page))
 
 	page.OutFile = ProgramState.GetPageOutpath(page)
 
@@ -143,15 +143,15 @@ func ParsePost(file string, ProgramState *State.CompilerState) (DataTypes.Page,
 
 /**
  * Name.........: ApplyDefaultMetaConfig
- * Parameters...: meta (map[string]string) - the config to apply defualts to
+ * Parameters...: meta (map[string]DataTypes.Value) - the config to apply defualts to
  *                ProgarmState (*State.CompilerState) - Compiler state
  * Description..: Adds default options to a configuration if they do not exist
  */
-func ApplyDefaultMetaConfig(meta map[string]string, ProgramState *State.CompilerState) {
+func ApplyDefaultMetaConfig(meta map[string]DataTypes.Value, ProgramState *State.CompilerState) {
 	defaults := []string{"author", "description", "title", "template"}
 
 	for _, key := range defaults {
-		if meta["page."+key] == "" {
+		if meta["page."+key].String() == "" {
 			meta["page."+key] = ProgramState.Config["site."+key]
 		}
 	}
@@ -164,7 +164,7 @@ func ApplyDefaultMetaConfig(meta map[string]string, ProgramState *State.Compiler
  * Description..:
  */
 func GetExcerpt(page *DataTypes.Page, ProgramState *State.CompilerState) {
-	if page.Meta["page.excerpt"] != "" {
+	if page.Meta["page.excerpt"].String() != "" {
 		return
 	}
 
@@ -173,14 +173,15 @@ func GetExcerpt(page *DataTypes.Page, ProgramState *State.CompilerState) {
 	for _, origLine := range page.Content {
 		line := Helpers.Trim(origLine)
 
-		if line == ProgramState.Config["blog.excerpt"] {
+		if line == ProgramState.Setting("blog.excerpt") {
 			break // Exit for loop
 		} else {
 			excerpt = append(excerpt, line)
 		}
 	}
 
-	page.Meta["page.excerpt"] = Helpers.Join(excerpt, "\n")
+	// The excerpt is part of the content, so it is trusted just like the content
+	page.Meta["page.excerpt"] = DataTypes.NewSafeString(Helpers.Join(excerpt, "\n"))
 }
 
 /**
@@ -193,21 +194,44 @@ func GetExcerpt(page *DataTypes.Page, ProgramState *State.CompilerState) {
 func ExpandPage(pageInfo *DataTypes.Page, ProgramState *State.CompilerState) Errors.Error {
 	page := *pageInfo
 
-	if page.Meta["page.template"] == "" {
+	if page.Meta["page.template"].String() == "" {
 		return Errors.NewWarning("No template specified for ", page.File, " it will not be expanded.")
 	}
 
-	// Get the contents of the template file, this is a place to start
-	contents, err := FileSystem.ReadFile(ProgramState.Template(page.Meta["page.template"] + ".html"))
+	// Get the template and every template it extends, this is a place to start
+	template := ProgramState.Template(TemplateFile(page.Meta["page.template"].String()))
+	nodes, blocks, err := LoadTemplate(page.Meta["page.template"].String(), ProgramState)
 	if err.HasError() {
 		return err
 	}
 
-	// Expand the template with the file contents and stuff
-	ProgramState.Meta.Push(page.Meta)
+	meta := make(map[string]DataTypes.Value)
+	for key, val := range page.Meta {
+		meta[key] = val
+	}
+
+	ProgramState.Meta.Push(meta, DataTypes.PageScope)
 	ProgramState.CurrentPage = page
-	ExpandContent(&contents, ProgramState)
+	ProgramState.Macros = make(map[string]DataTypes.MacroNode)
+
+	// Expand the page itself first, the template gets the result as {{ content }}
+	body := Helpers.Copy(page.Content)
+	err = ExpandContent(&body, ProgramState)
+	if err.HasError() {
+		ProgramState.Meta.Pop()
+		return Errors.NewFatal(page.File, ": ", err.Msg)
+	}
+	meta["content"] = DataTypes.NewSafeString(Helpers.Join(body, "\n"))
+
+	// Expand the template with the file contents and stuff, blocks use the definition of the last template to override them
+	ProgramState.Blocks = blocks
+	result, err := Semantics.EvaluateTemplate(nodes, ProgramState)
+	ProgramState.Blocks = make(map[string][][]DataTypes.Node)
 	ProgramState.Meta.Pop()
+	if err.HasError() {
+		return Errors.NewFatal(template, ": ", err.Msg)
+	}
+	contents := Helpers.Split(Helpers.TrimSuffix(result, "\n"), "\n")
 
 	// Write to the output directory
 	err = FileSystem.WriteFile(page.OutFile, contents)
@@ -228,207 +252,21 @@ func ExpandPage(pageInfo *DataTypes.Page, ProgramState *State.CompilerState) Err
  * Name.........: ExpandContent
  * Parameters...: content (*[]string) - the content to expand
  *                ProgarmState (*State.CompilerState) - Compiler state
- * Description..: Expands contents for ExpandPage into the build directory
+ * Return.......: Errors.Error - any errors
+ * Description..: Parses the content as a template and evaluates it in place
  */
-func ExpandContent(content *[]string, ProgramState *State.CompilerState) {
-	page := *content
-
-	cmdStack := DataTypes.CommandStack{}
-	ProgramState.CurrentPage.Meta["page.x"] = "66"
-
-	partOfCmd := false
-
-	//Helpers.Print("yellow", "Page: ", Helpers.Join(page, "\n"))
-	// Loop through lines
-	for i, origLine := range page {
-		line := Helpers.Trim(origLine)
-		//Helpers.Print("green", "Line: ", line)
-		// Break up new lines
-		lineBreaks := Helpers.Split(line, "\n")
-		if len(lineBreaks) > 1 {
-			Helpers.Remove(&page, i, i)
-			Helpers.Inject(&page, lineBreaks, i)
-
-			ExpandContent(&page, ProgramState)
-			break
-		}
-
-		// Check if it an include
-		isInclude, fileName := Grammar.IsIncludeStatement(line)
-		if isInclude {
-			includeContents, err := FileSystem.ReadFile(ProgramState.Include(fileName))
-			if !err.HasError() {
-				Helpers.Remove(&page, i, i)
-				Helpers.Inject(&page, includeContents, i) // Insert the contents of the incldued file
-
-				// Recursively to evaluate stuff in the included file
-				ExpandContent(&page, ProgramState)
-				break
-			}
-		}
-
-		// Check if it is a set command
-		isSet, setVar, setVal := Grammar.IsSetCommand(line)
-		if isSet {
-			if cmdStack.Length() == 0 {
-				//Helpers.Print("Cyan", setVar, " = ", setVal)
-				Semantics.EvaluateSetCommand(setVar, setVal, ProgramState)
-				page[i] = ""
-				//Helpers.Remove(&page, i, i)
-				continue
-				//ExpandContent(&page, ProgramState)
-				//break
-			} //else {
-			//Helpers.Print("cyan", "set in a command", cmdStack.Peek().Control)
-			//}
-		}
-
-		inForEachLoop := false
-		if cmdStack.Length() > 0 {
-			inForEachLoop = (cmdStack.Peek()).Control == "foreach"
-		}
-
-		// Only process if statements, foreach loops, and print commands if you are not inside of a foreach loop
-		if !inForEachLoop {
-			// Check for an inline print statement
-			inlinePrints := Grammar.FindInlinePrints(line)
-
-			if len(inlinePrints) > 0 {
-				// Evaluate all of the inline commands
-				for _, cmd := range inlinePrints {
-					evaluated := Semantics.EvaluatePrintCommand(cmd, ProgramState)
-
-					page[i] = Helpers.Replace(page[i], cmd, evaluated)
-					line = Helpers.Trim(page[i])
-				}
-
-				// Breakup line breaks after evaluating the print command (specifically for {{ content }} or {{ *.excerpt }})
-				expanded := Helpers.Split(line, "\n")
-				if len(expanded) > 1 {
-					Helpers.Remove(&page, i, i)
-					Helpers.Inject(&page, expanded, i)
-					ExpandContent(&page, ProgramState)
-					break
-				}
-
-				// Look for command that ends something
-			} else if Grammar.EndsMultilineCommand(line) {
-				whatItEnds := Grammar.WhatDoesEndCommandEnd(line) // Find out what it ends
-				// Pull command from stack
-				cmd, _ := cmdStack.Pop()
-				cmd.EndLine = i
-				cmd.State = 0
-				//Helpers.Print("magenta", "End of multiline: ", line, " => ", whatItEnds, " => ", cmd.Control)
-
-				// Only process if it ends the command on top of the stack
-				if whatItEnds == cmd.Control {
-					toInject := []string{}
-
-					// Process if statement
-					if cmd.Control == "if" {
-						// Evaluate Conditional
-						if Semantics.IsTrue(cmd.Condition, ProgramState) {
-							toInject = cmd.IfTrue
-						} else {
-							toInject = cmd.IfFalse
-						}
-
-						// Inject the evaluated if statement where the raw if statement was
-						Helpers.Remove(&page, cmd.StartLine, cmd.EndLine)
-						Helpers.Inject(&page, toInject, cmd.StartLine)
-
-						//Helpers.Print("white", "Adding ", Helpers.Join(toInject, "\n"))
-
-						ExpandContent(&page, ProgramState)
-						break // Do not continue processes, the recursive call above will do that
-					}
-				} else {
-					//Helpers.Print("white", "Adding2 ", cmd.Control)
-					cmdStack.Push(cmd) // Put back onto the stack if not ending the topmost command
-				}
-
-				// Check if the line starts a multiline command (if, foreach)
-			} else if Grammar.StartsMultilineCommand(line) {
-				cmd := Semantics.GetCommand(line)
-				cmd.StartLine = i
-
-				//Helpers.Print("magenta", "Start of multiline: ", line)
-				cmdStack.Push(cmd)
-				partOfCmd = true // Do not add this line to the command ifTrue/ifFalse
-
-			}
-		} else { // End if !inForEachLoop
-			if Grammar.EndsMultilineCommand(line) {
-				whatItEnds := Grammar.WhatDoesEndCommandEnd(line) // Find out what it ends
-
-				// Pull command from stack
-				cmd, _ := cmdStack.Pop()
-				cmd.EndLine = i
-				cmd.State = 0
-
-				// Only process if it ends the command on top of the stack
-				if whatItEnds == cmd.Control {
-					if cmd.Control == "foreach" {
-						// Perform foreach
-						variable, alias := Semantics.ParseForEachCondition(cmd.Condition)
-
-						foreachResult := []string{}
-
-						for _, pg := range ProgramState.GetSpecial(variable) {
-							// Rename the meta keys to the alias specified in the foreach loop
-							newMeta := make(map[string]string)
-							for key, val := range pg.Meta {
-								keys := Helpers.Split(key, ".")
-								newKey := keys[0]
-
-								if len(keys) > 1 {
-									newKey = alias + "." + Helpers.Join(keys[1:], ".")
-								}
-								newMeta[newKey] = val
-							}
-
-							thisLoop := Helpers.Copy(cmd.IfTrue)
-
-							// Push the new meta to the meta stack, and evaluate the for each loop
-							ProgramState.Meta.Push(newMeta)
-							ExpandContent(&thisLoop, ProgramState)
-							ProgramState.Meta.Pop()
-
-							//Helpers.Print("red", "Expanded For: ", Helpers.Join(thisLoop, "\n"))
-							// Add to the foreach loop results
-							foreachResult = append(thisLoop, foreachResult...)
-						}
-
-						// Display the foreach loop results
-						Helpers.Remove(&page, cmd.StartLine, cmd.EndLine)
-						Helpers.Inject(&page, foreachResult, cmd.StartLine)
-
-						ExpandContent(&page, ProgramState)
-						break
-					}
-
-				} else {
-					//Helpers.Print("white", "Adding ", cmd.Control)
-					cmdStack.Push(cmd) // Put back onto the stack if not ending the topmost command
-				}
-			}
-		}
-
-		// Add line to the command at the top of the stack if there is one
-		if cmdStack.Length() > 0 && !partOfCmd {
-			cmd, _ := cmdStack.Pop()
-
-			if cmd.State == 0 {
-				cmd.IfTrue = append(cmd.IfTrue, origLine)
-			} else if cmd.State == 1 {
-				cmd.IfFalse = append(cmd.IfFalse, origLine)
-			}
-
-			cmdStack.Push(cmd)
-		}
+func ExpandContent(content *[]string, ProgramState *State.CompilerState) Errors.Error {
+	// Every line ends with a line break, just like in the file
+	nodes, err := Grammar.ParseTemplate(Helpers.Join(*content, "\n")+"\n", ProgramState.Syntax())
+	if err.HasError() {
+		return err
+	}
 
-		partOfCmd = false
-	} // End loop through lines
+	result, err := Semantics.EvaluateTemplate(nodes, ProgramState)
+	if err.HasError() {
+		return err
+	}
 
-	*content = page // Give back to caller function
+	*content = Helpers.Split(Helpers.TrimSuffix(result, "\n"), "\n") // Give back to caller function
+	return Errors.None()
 }
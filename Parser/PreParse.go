@@ -1,10 +1,12 @@
 package Parser
 
 import (
+	"daphne/DataTypes"
 	"daphne/FileSystem"
 	"daphne/Helpers"
 	"daphne/State"
 	"regexp"
+	"sort"
 )
 
 var validFileExtensions, _ = regexp.Compile("^[^_\\.](.*)?$")
@@ -15,6 +17,10 @@ var validFileExtensions, _ = regexp.Compile("^[^_\\.](.*)?$")
  * Description..: Preparses (discovers) files
  */
 func PreparseFiles(dir string, ProgramState *State.CompilerState) {
+	// Every page can use the data files
+	err := ParseDataFiles(ProgramState)
+	err.Handle()
+
 	files := FileSystem.CollapseDirectory(dir, "", true) // Get all the files in the directory
 
 	// Loop through them
@@ -31,7 +37,7 @@ func PreparseFiles(dir string, ProgramState *State.CompilerState) {
 
 			if ext == "html" || ext == "htm" {
 				// If in the posts directory then parse as a post
-				if file.Directory == ProgramState.Config["compiler.posts_dir"] {
+				if file.Directory == ProgramState.Setting("compiler.posts_dir") {
 					page, err := ParsePost(name, ProgramState)
 					err.Handle()
 					page.IsBlogPost = true
@@ -68,4 +74,77 @@ func PreparseFiles(dir string, ProgramState *State.CompilerState) {
 			}
 		}
 	}
+
+	SortCollections(ProgramState)
+}
+
+/**
+ * Name.........: SortCollections
+ * Parameters...: ProgramState (*State.CompilerState) - The State
+ * Description..: Puts every collection in a fixed order so builds are the same on every machine,
+ *                posts go newest first and everything else goes by weight and then by path
+ */
+func SortCollections(ProgramState *State.CompilerState) {
+	for name, pages := range ProgramState.Special {
+		if name == "site.posts" {
+			SortPosts(pages)
+		} else {
+			SortPages(pages)
+		}
+	}
+}
+
+/**
+ * Name.........: SortPosts
+ * Parameters...: posts ([]DataTypes.Page) - the posts to sort
+ * Description..: Sorts posts newest first, posts from the same day are sorted by file name
+ */
+func SortPosts(posts []DataTypes.Page) {
+	sort.SliceStable(posts, func(a int, b int) bool {
+		dateA := posts[a].Meta["page.date_short"].String()
+		dateB := posts[b].Meta["page.date_short"].String()
+
+		if dateA != dateB {
+			return dateA > dateB
+		}
+		return posts[a].File < posts[b].File
+	})
+}
+
+/**
+ * Name.........: SortPages
+ * Parameters...: pages ([]DataTypes.Page) - the pages to sort
+ * Description..: Sorts pages by their weight (or order) from smallest to largest, pages without one go last.
+ *                Pages with the same weight are sorted by path
+ */
+func SortPages(pages []DataTypes.Page) {
+	sort.SliceStable(pages, func(a int, b int) bool {
+		weightA, hasWeightA := GetPageWeight(pages[a])
+		weightB, hasWeightB := GetPageWeight(pages[b])
+
+		if hasWeightA != hasWeightB {
+			return hasWeightA
+		}
+		if weightA != weightB {
+			return weightA < weightB
+		}
+		return pages[a].File < pages[b].File
+	})
+}
+
+/**
+ * Name.........: GetPageWeight
+ * Parameters...: page (DataTypes.Page) - the page
+ * Return.......: float64 - the weight of the page
+ *                bool - false if the page does not have a weight
+ * Description..: Gets the weight of a page from its weight or order meta
+ */
+func GetPageWeight(page DataTypes.Page) (float64, bool) {
+	for _, key := range []string{"page.weight", "page.order"} {
+		if weight, isNum := Helpers.ToFloat(page.Meta[key].String()); isNum {
+			return weight, true
+		}
+	}
+
+	return 0, false
 }
@@ -0,0 +1,84 @@
+package Parser
+
+import (
+	"daphne/DataTypes"
+	"daphne/Errors"
+	"daphne/FileSystem"
+	"daphne/Grammar"
+	"daphne/Helpers"
+	"daphne/State"
+	"path/filepath"
+)
+
+/**
+ * Name.........: LoadTemplate
+ * Parameters...: name (string) - the template, with or without .html
+ *                ProgramState (*State.CompilerState) - Compiler state
+ * Return.......: []DataTypes.Node - the template that does not extend any other, this is the one that is evaluated
+ *                map[string][][]DataTypes.Node - every definition of every block, from the given template to the last one extended
+ *                Errors.Error - any errors
+ * Description..: Parses a template and every template it extends
+ */
+func LoadTemplate(name string, ProgramState *State.CompilerState) ([]DataTypes.Node, map[string][][]DataTypes.Node, Errors.Error) {
+	blocks := make(map[string][][]DataTypes.Node)
+	chain := []string{}
+
+	for {
+		file := TemplateFile(name)
+
+		// A template can not extend itself, even through other templates
+		for _, seen := range chain {
+			if seen == file {
+				return nil, nil, Errors.NewFatal("Templates extend each other: ", Helpers.Join(append(chain, file), " extends "))
+			}
+		}
+		chain = append(chain, file)
+
+		path := ProgramState.Template(file)
+		contents, err := FileSystem.ReadFile(path)
+		if err.HasError() {
+			return nil, nil, err
+		}
+
+		nodes, err := Grammar.ParseTemplate(Helpers.Join(contents, "\n")+"\n", ProgramState.Syntax())
+		if err.HasError() {
+			return nil, nil, Errors.NewFatal(path, ": ", err.Msg)
+		}
+
+		templateBlocks := make(map[string][]DataTypes.Node)
+		err = Grammar.CollectBlocks(nodes, templateBlocks)
+		if err.HasError() {
+			return nil, nil, Errors.NewFatal(path, ": ", err.Msg)
+		}
+
+		for block, body := range templateBlocks {
+			blocks[block] = append(blocks[block], body)
+		}
+
+		extends, err := Grammar.FindExtends(nodes, ProgramState.Syntax())
+		if err.HasError() {
+			return nil, nil, Errors.NewFatal(path, ": ", err.Msg)
+		}
+
+		if extends == nil {
+			return nodes, blocks, Errors.None()
+		}
+		name = extends.Template
+	}
+}
+
+/**
+ * Name.........: TemplateFile
+ * Parameters...: name (string) - the name of a template
+ * Return.......: string - the file name of the template
+ * Description..: Adds .html to the name of a template if it has no extension
+ */
+func TemplateFile(name string) string {
+	name = Helpers.Trim(name)
+
+	if filepath.Ext(name) == "" {
+		return name + ".html"
+	}
+
+	return name
+}
@@ -3,21 +3,49 @@ package State
 import (
 	"daphne/DataTypes"
 	"daphne/Helpers"
+	"path/filepath"
 )
 
 type SpecialFunction func(DataTypes.Page, *CompilerState)
 
+/**
+ * A block being evaluated, level is the index of its definition in CompilerState.Blocks
+ */
+type BlockLevel struct {
+	Name  string
+	Level int
+}
+
+/**
+ * What a {% break %} or {% continue %} asks of the foreach loop around it
+ */
+type LoopJump int
+
+const (
+	NoJump       LoopJump = iota
+	BreakJump             // Stop the loop
+	ContinueJump          // Go on with the next item
+)
+
 /**
  * A struct to represent the current State
  */
 type CompilerState struct {
-	Config  map[string]string
+	Config  map[string]DataTypes.Value
 	Special map[string][]DataTypes.Page
 	Ignore  []string
 
 	CurrentPage DataTypes.Page
 	Meta        DataTypes.MetaStack
 
+	Blocks     map[string][][]DataTypes.Node // Every definition of a block, from the template being expanded to the one it extends
+	BlockStack []BlockLevel
+
+	Macros    map[string]DataTypes.MacroNode // Macros of the page being expanded, by the name they are called with
+	Namespace string                         // The namespace of the macro being evaluated
+
+	Jump LoopJump // Set by a break or continue until the loop it is in sees it
+
 	PerformAfterFileWrite []SpecialFunction
 }
 
@@ -27,9 +55,11 @@ type CompilerState struct {
 func NewCompilerState() *CompilerState {
 	state := new(CompilerState)
 
-	state.Config = make(map[string]string)
+	state.Config = make(map[string]DataTypes.Value)
 	state.Meta = DataTypes.MetaStack{}
 	state.Ignore = []string{}
+	state.Blocks = make(map[string][][]DataTypes.Node)
+	state.Macros = make(map[string]DataTypes.MacroNode)
 
 	state.PerformAfterFileWrite = []SpecialFunction{}
 
@@ -40,47 +70,161 @@ func NewCompilerState() *CompilerState {
  * Returns true if a variable exists
  */
 func (self CompilerState) Exists(variable string) bool {
-	return self.Config[variable] != "" || self.CurrentPage.Meta[variable] != "" || (self.Meta.Peek())[variable] != ""
+	return !self.Get(variable).IsNull()
+}
+
+/**
+ * Name.........: Get
+ * Parameters...: variable (string) - the name of the variable
+ * Return.......: DataTypes.Value - the value, null if it does not exist
+ * Description..: Retrieves a variable, starting at the innermost scope and walking out to the page, then the
+ *                config. include.* is only looked up in the nearest include, and a macro only sees its own
+ *                scope and the page
+ */
+func (self CompilerState) Get(variable string) DataTypes.Value {
+	variable = Helpers.ToLower(Helpers.Trim(variable))
+	isInclude := variable == "include" || Helpers.Substring(variable, 0, 7) == "include."
+	inMacro := false
+
+	for i := self.Meta.Length() - 1; i >= 0; i-- {
+		scope, kind := self.Meta.Scope(i)
+		if inMacro && kind != DataTypes.PageScope {
+			continue
+		}
+
+		if value := Lookup(scope, variable); !value.IsNull() {
+			return value
+		}
+
+		if kind == DataTypes.IncludeScope && isInclude {
+			return DataTypes.Value{}
+		}
+		inMacro = inMacro || kind == DataTypes.MacroScope
+	}
+
+	for _, scope := range []map[string]DataTypes.Value{self.CurrentPage.Meta, self.Config} {
+		if value := Lookup(scope, variable); !value.IsNull() {
+			return value
+		}
+	}
+
+	// The collections of pages, like site.posts
+	for name := range self.Special {
+		if variable == name {
+			return self.Collection(name)
+		} else if len(variable) > len(name) && variable[:len(name)+1] == name+"." {
+			value := self.Collection(name)
+			for _, property := range Helpers.Split(variable[len(name)+1:], ".") {
+				value = value.Property(property)
+			}
+			return value
+		}
+	}
+
+	return DataTypes.Value{}
 }
 
 /**
- * Retrieves a variable from the compiler state
+ * Name.........: Collection
+ * Parameters...: name (string) - the name of the collection, like site.posts
+ * Return.......: DataTypes.Value - a list with the meta of every page in the collection, as maps
+ * Description..: Gets a collection of pages as a value, in the same order foreach loops go through it
  */
-func (self CompilerState) Get(variable string) string {
-	if (self.Meta.Peek())[variable] != "" {
-		return (self.Meta.Peek())[variable]
-	} else if self.CurrentPage.Meta[variable] != "" {
-		return self.CurrentPage.Meta[variable]
-	} else if self.Config[variable] != "" {
-		return self.Config[variable]
+func (self CompilerState) Collection(name string) DataTypes.Value {
+	items := []DataTypes.Value{}
+
+	for _, page := range self.Special[name] {
+		items = append(items, Lookup(page.Meta, "page"))
 	}
 
-	return ""
+	return DataTypes.NewList(items)
 }
 
 /**
- * Sets the value of a variable if possible
+ * Name.........: Lookup
+ * Parameters...: scope (map[string]DataTypes.Value) - the variables to look in
+ *                variable (string) - the name of the variable
+ * Return.......: DataTypes.Value - the value, null if it does not exist
+ * Description..: Finds a variable in a scope. Variables are stored by their full name (site.url), when
+ *                there is no variable with that name it can be a property of one (post.tags.0), or all of
+ *                the variables that start with the name (site.social) as a map
  */
-func (self *CompilerState) Set(variable string, value string) {
-	variable = Helpers.ToLower(variable)
+func Lookup(scope map[string]DataTypes.Value, variable string) DataTypes.Value {
+	if value, exists := scope[variable]; exists {
+		return value
+	}
 
-	if (*self).Config[variable] != "" {
-		(*self).Config[variable] = value
-	} else if ((*self).Meta.Peek())[variable] != "" {
-		((*self).Meta.Peek())[variable] = value
+	// Property of a shorter variable
+	path := Helpers.Split(variable, ".")
+	for i := len(path) - 1; i > 0; i-- {
+		value, exists := scope[Helpers.Join(path[:i], ".")]
+		if !exists {
+			continue
+		}
+
+		for _, name := range path[i:] {
+			value = value.Property(name)
+		}
+		return value
+	}
+
+	// Every variable under the name
+	prefix := variable + "."
+	children := make(map[string]DataTypes.Value)
+	for key := range scope {
+		if len(key) > len(prefix) && key[:len(prefix)] == prefix {
+			name := Helpers.Split(key[len(prefix):], ".")[0]
+			children[name] = Lookup(scope, prefix+name)
+		}
+	}
+
+	if len(children) > 0 {
+		return DataTypes.NewMap(children)
+	}
+
+	return DataTypes.Value{}
+}
+
+/**
+ * Name.........: Set
+ * Parameters...: variable (string) - the name of the variable
+ *                value (DataTypes.Value) - the new value
+ *                global (bool) - set it for the whole page instead of the innermost scope
+ * Description..: Sets a variable, it exists until the scope it was set in ends. The config is never changed,
+ *                so nothing set while expanding one page is seen by the next
+ */
+func (self *CompilerState) Set(variable string, value DataTypes.Value, global bool) {
+	variable = Helpers.ToLower(Helpers.Trim(variable))
+
+	if self.Meta.Length() == 0 {
+		if self.CurrentPage.Meta == nil {
+			self.CurrentPage.Meta = make(map[string]DataTypes.Value)
+		}
+		self.CurrentPage.Meta[variable] = value
 		return
 	}
 
-	//Helpers.Print("yellow", "Setting ", variable, " = ", value)
-	// Create New
-	(*self).Config[variable] = value
+	index := self.Meta.Length() - 1
+	if global {
+		index = 0
+	}
+
+	scope, _ := self.Meta.Scope(index)
+	scope[variable] = value
+}
+
+/**
+ * Gets a config value as a string
+ */
+func (self CompilerState) Setting(name string) string {
+	return self.Config[name].String()
 }
 
 /**
  * Gets a path in the compiler source
  */
 func (self CompilerState) Path(file string) string {
-	path := self.Config["compiler.source"] + "\\" + file
+	path := self.Setting("compiler.source") + "\\" + file
 
 	return Helpers.Replace(Helpers.Replace(path, "\\\\", "\\"), ".\\", "")
 }
@@ -89,21 +233,50 @@ func (self CompilerState) Path(file string) string {
  * Gets the file path in the output
  */
 func (self CompilerState) OutputPath(file string) string {
-	return self.Path(self.Config["compiler.output"] + "\\" + file)
+	return self.Path(self.Setting("compiler.output") + "\\" + file)
 }
 
 /**
  * Gets the path to a file in the _includes dir
  */
 func (self CompilerState) Include(file string) string {
-	return self.Path(self.Config["compiler.include_dir"] + "\\" + file)
+	return self.Path(self.Setting("compiler.include_dir") + "\\" + file)
+}
+
+/**
+ * Name.........: EscapeHTML
+ * Return.......: bool
+ * Description..: True if printed values are escaped, which they are for pages that become .html files unless
+ *                compiler.autoescape is false
+ */
+func (self CompilerState) EscapeHTML() bool {
+	ext := Helpers.ToLower(filepath.Ext(self.CurrentPage.OutFile))
+
+	return (ext == ".html" || ext == ".htm") && Helpers.ToLower(self.Setting("compiler.autoescape")) != "false"
+}
+
+/**
+ * Name.........: Syntax
+ * Return.......: DataTypes.Syntax
+ * Description..: How templates are written, from the compiler section of the config
+ */
+func (self CompilerState) Syntax() DataTypes.Syntax {
+	return DataTypes.Syntax{
+		TagOpening:     self.Setting("compiler.tags.opening"),
+		TagClosing:     self.Setting("compiler.tags.closing"),
+		PrintOpening:   self.Setting("compiler.tags.print.opening"),
+		PrintClosing:   self.Setting("compiler.tags.print.closing"),
+		CommentOpening: self.Setting("compiler.tags.comment.opening"),
+		CommentClosing: self.Setting("compiler.tags.comment.closing"),
+		TrimTagLines:   Helpers.ToLower(self.Setting("compiler.trim_tag_lines")) == "true",
+	}
 }
 
 /**
  * Gets the path a file in the templates dir
  */
 func (self CompilerState) Template(file string) string {
-	return self.Path(self.Config["compiler.template_dir"] + "\\" + file)
+	return self.Path(self.Setting("compiler.template_dir") + "\\" + file)
 }
 
 /**
@@ -144,7 +317,7 @@ func (self CompilerState) IgnoreDirDuringWatch(dir string) bool {
 	if dir[:1] == "_" {
 		dir = dirPath[0]
 
-		return dir == self.Config["compiler.output"]
+		return dir == self.Setting("compiler.output")
 	}
 	return (dir[:1] == "." && len(dir) > 1)
 }
@@ -160,13 +333,13 @@ func (self CompilerState) GetPageOutpath(page DataTypes.Page) string {
 		filePath = filePath[1:]
 	}
 
-	if filePath[0] == self.Config["compiler.posts_dir"] {
+	if filePath[0] == self.Setting("compiler.posts_dir") {
 		page.IsBlogPost = true
 
 		// Get Permalink structure
-		permalink := page.GetPermalink(self.Config["blog.permalink"])
+		permalink := page.GetPermalink(self.Setting("blog.permalink"))
 
-		if self.Config["blog.foldericize"] != "true" {
+		if self.Setting("blog.foldericize") != "true" {
 			permalink = permalink + ".html"
 		} else {
 			permalink = permalink + "\\index.html"
@@ -179,7 +352,7 @@ func (self CompilerState) GetPageOutpath(page DataTypes.Page) string {
 }
 
 func (self CompilerState) GetPageURL(page DataTypes.Page) string {
-	file := page.Meta["page.file"]
+	file := page.Meta["page.file"].String()
 	filePath := Helpers.Split(file, "\\")
 
 	name := filePath[len(filePath)-1]
@@ -198,17 +371,17 @@ func (self CompilerState) GetPageURL(page DataTypes.Page) string {
 		url = path + "\\" + name + "." + extension
 	}
 
-	return self.Config["site.url"] + Helpers.Replace(self.Path(url), "\\", "/")
+	return self.Setting("site.url") + Helpers.Replace(self.Path(url), "\\", "/")
 }
 
 func (self CompilerState) GetPostURL(page DataTypes.Page) string {
-	permalink := page.GetPermalink(self.Config["blog.permalink"])
+	permalink := page.GetPermalink(self.Setting("blog.permalink"))
 
-	if self.Config["blog.foldericize"] != "true" {
+	if self.Setting("blog.foldericize") != "true" {
 		permalink = permalink + ".html"
 	} else {
 		permalink = permalink + "\\"
 	}
 
-	return self.Config["site.url"] + Helpers.Replace(self.Path(permalink), "\\", "/")
+	return self.Setting("site.url") + Helpers.Replace(self.Path(permalink), "\\", "/")
 }
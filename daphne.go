@@ -98,7 +98,7 @@ func PreBuild(wd string) {
     err.Handle()
 
     // Clear the output directory
-    FileSystem.EmptyDir(ProgramState.Config["compiler.output"])
+    FileSystem.EmptyDir(ProgramState.Setting("compiler.output"))
 }
 
 
@@ -109,7 +109,7 @@ func PreBuild(wd string) {
   */
 func Build(wd string) {
     // Preparse all the files so they can reference one another
-    Parser.PreparseFiles(ProgramState.Config["compiler.source"], ProgramState)
+    Parser.PreparseFiles(ProgramState.Setting("compiler.source"), ProgramState)
 
     Helpers.Print("White", "Building...")
 
@@ -118,7 +118,7 @@ func Build(wd string) {
         for _, page := range ProgramState.GetSpecial("site." + pageType) {
             displayText := page.File
             if pageType == "posts" {
-                displayText = page.Meta["page.title"]
+                displayText = page.Meta["page.title"].String()
             }
 
             Helpers.Print("Magenta", "\tBuilding: ", displayText)
@@ -170,9 +170,10 @@ func NewProject() {
 
 /**
   * Name.........: NewPost
-  * Description..: Creates a new post
+  * Description..: Creates a new post, with the meta tags from the configuration
   */
 func NewPost() {
+    // The posts folder and the meta tags come from the config, PreBuild has already read it
     reader := bufio.NewReader(os.Stdin)
 
     fmt.Print("Post Title: ")
@@ -182,11 +183,12 @@ func NewPost() {
 
     t := time.Now()
 
-    path := ProgramState.Config["compiler.posts_dir"] + "\\" + t.Format("2006-01-02") + "-" + Helpers.URLSafe(title) + ".html"
-    images := ProgramState.Config["compiler.posts_image_dir"] + "\\" + Helpers.URLSafe(title)
+    path := ProgramState.Setting("compiler.posts_dir") + "\\" + t.Format("2006-01-02") + "-" + Helpers.URLSafe(title) + ".html"
+    images := ProgramState.Setting("compiler.posts_image_dir") + "\\" + Helpers.URLSafe(title)
 
     // Create the post file and the directory
-    FileSystem.WriteFile(path, []string{"---", "title: " + title, "template: post", "---"})
+    meta := ProgramState.Setting("compiler.tags.meta")
+    FileSystem.WriteFile(path, []string{meta, "title: " + title, "template: post", meta})
     FileSystem.CreateDir(images)
 
 
@@ -200,12 +202,12 @@ func NewPost() {
   */
 func Serve(wd string) {
     // Modify the URL of the website
-    ProgramState.Config["site.url"] = "http://localhost:8081/"
+    ProgramState.Config["site.url"] = DataTypes.NewString("http://localhost:8081/")
 
     // Start the web server
-    http.Handle("/", http.FileServer(http.Dir("./" + ProgramState.Config["compiler.output"])))
+    http.Handle("/", http.FileServer(http.Dir("./" + ProgramState.Setting("compiler.output"))))
 
-    Helpers.Print("Yellow", "\n\nWeb Server Started: ", ProgramState.Config["site.url"])
+    Helpers.Print("Yellow", "\n\nWeb Server Started: ", ProgramState.Setting("site.url"))
     go http.ListenAndServe(":8081", nil)
     Watch(wd)
 }